@@ -0,0 +1,239 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tobg/scheduler/models"
+)
+
+// DefaultKeepReleases is the number of releases kept when the task does not provide one
+const DefaultKeepReleases = 5
+
+const (
+	releasesDir   = "releases"
+	currentLink   = "current"
+	releaseFormat = "20060102T150405.000000"
+)
+
+// DeploySchema describes the params of the deploy task
+var DeploySchema = models.ActionSchema{
+	Description: "installs an artifact as a new release of a service and switches to it",
+	Params: []models.ParamSchema{
+		{Name: "service", Type: models.ParamString, Required: true, NoTemplates: true, Description: "name of the service"},
+		{Name: "deployment_path", Type: models.ParamString, Required: true, NoTemplates: true, Description: "directory of the service, under /home/apps/<service>"},
+		{Name: "artifact", Type: models.ParamString, Required: true, Description: "absolute path of a directory, .tar, .tar.gz, .tgz, .zip or single file"},
+		{Name: "keep", Type: models.ParamInteger, Description: "number of releases to keep, defaults to 5"},
+	},
+}
+
+// DeployParams are the params of the deploy task
+type DeployParams struct {
+	Service        string `json:"service"`
+	DeploymentPath string `json:"deployment_path"`
+	Artifact       string `json:"artifact"`
+	Keep           int    `json:"keep,omitempty"`
+}
+
+// Deploy installs an artifact as a new release of a service and switches to it.
+//
+// The artifact (directory, .tar, .tar.gz, .tgz, .zip or single file) is staged in
+// <deployment path>/releases/<release id>, the <deployment path>/current symlink is
+// then atomically replaced to point to it and the oldest releases are pruned.
+// A cancelled context stops the staging, the current release is then left untouched.
+// The outputs hold the release id and its directory.
+func Deploy(ctx context.Context, params models.Params) (models.TaskOutputs, error) {
+	var p DeployParams
+	err := params.Decode(&p)
+	if err != nil {
+		return nil, models.Permanent(err)
+	}
+
+	keep := p.Keep
+	if keep == 0 {
+		keep = DefaultKeepReleases
+	}
+	if keep < 1 {
+		return nil, models.Permanent(fmt.Errorf("invalid number of releases to keep: %d", keep))
+	}
+
+	release, err := stageRelease(ctx, p.DeploymentPath, p.Artifact)
+	if err != nil {
+		return nil, fmt.Errorf("could not stage release: %w", err)
+	}
+
+	// the task may have been abandoned while staging, the release must not become current
+	err = ctx.Err()
+	if err != nil {
+		os.RemoveAll(filepath.Join(p.DeploymentPath, releasesDir, release))
+		return nil, err
+	}
+
+	err = switchCurrent(p.DeploymentPath, release)
+	if err != nil {
+		return nil, fmt.Errorf("could not switch current release: %w", err)
+	}
+
+	err = pruneReleases(p.DeploymentPath, keep)
+	if err != nil {
+		return nil, fmt.Errorf("could not prune old releases: %w", err)
+	}
+
+	return models.TaskOutputs{
+		"release":      release,
+		"release_path": filepath.Join(p.DeploymentPath, releasesDir, release),
+	}, nil
+}
+
+// stageRelease installs the artifact in a temporary directory and renames it
+// to its final release directory once complete, it returns the release id
+func stageRelease(ctx context.Context, deploymentPath, artifact string) (string, error) {
+	info, err := os.Stat(artifact)
+	if err != nil {
+		return "", fmt.Errorf("could not read artifact: %w", err)
+	}
+
+	dir := filepath.Join(deploymentPath, releasesDir)
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return "", err
+	}
+
+	release := time.Now().UTC().Format(releaseFormat)
+	target := filepath.Join(dir, release)
+	if _, err := os.Stat(target); err == nil {
+		return "", fmt.Errorf("release %s already exists", release)
+	}
+
+	tmp, err := os.MkdirTemp(dir, "."+release+"-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	switch {
+	case info.IsDir():
+		err = copyDir(ctx, artifact, tmp)
+	case strings.HasSuffix(artifact, ".tar.gz"), strings.HasSuffix(artifact, ".tgz"):
+		err = extractTar(ctx, artifact, tmp, true)
+	case strings.HasSuffix(artifact, ".tar"):
+		err = extractTar(ctx, artifact, tmp, false)
+	case strings.HasSuffix(artifact, ".zip"):
+		err = extractZip(ctx, artifact, tmp)
+	default:
+		err = copyFile(ctx, artifact, filepath.Join(tmp, filepath.Base(artifact)), info.Mode())
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// last chance to give up before the release becomes visible
+	err = ctx.Err()
+	if err != nil {
+		return "", err
+	}
+
+	err = os.Chmod(tmp, 0o755)
+	if err != nil {
+		return "", err
+	}
+
+	err = os.Rename(tmp, target)
+	if err != nil {
+		return "", err
+	}
+
+	return release, nil
+}
+
+// switchCurrent creates a new symlink next to the current one and renames it
+// over the current one, rename being atomic the link is never missing
+func switchCurrent(deploymentPath, release string) error {
+	current := filepath.Join(deploymentPath, currentLink)
+	tmp := filepath.Join(deploymentPath, "."+currentLink+"-"+release)
+
+	err := os.Symlink(filepath.Join(releasesDir, release), tmp)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmp, current)
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
+
+// pruneReleases removes the oldest releases so that only the keep most recent
+// remain, the release pointed by the current symlink is never removed
+func pruneReleases(deploymentPath string, keep int) error {
+	dir := filepath.Join(deploymentPath, releasesDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var releases []string
+	for _, e := range entries {
+		if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			releases = append(releases, e.Name())
+		}
+	}
+
+	if len(releases) <= keep {
+		return nil
+	}
+
+	current, err := os.Readlink(filepath.Join(deploymentPath, currentLink))
+	if err != nil {
+		return err
+	}
+
+	// release ids are timestamps, lexical order is chronological order
+	sort.Strings(releases)
+	for _, r := range releases[:len(releases)-keep] {
+		if r == filepath.Base(current) {
+			continue
+		}
+
+		err := os.RemoveAll(filepath.Join(dir, r))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ParseDeployArgs converts the legacy args of the deploy task: service name,
+// deployment path, artifact path and optionally the number of releases to keep
+func ParseDeployArgs(args []string) (models.Params, error) {
+	if len(args) != 3 && len(args) != 4 {
+		return nil, errors.New("invalid number of arguments: expected service name, deployment path, artifact path and optional number of releases to keep")
+	}
+
+	params := models.Params{
+		"service":         args[0],
+		"deployment_path": args[1],
+		"artifact":        args[2],
+	}
+
+	if len(args) == 4 {
+		keep, err := strconv.Atoi(args[3])
+		if err != nil {
+			return nil, fmt.Errorf("invalid number of releases to keep: %v", args[3])
+		}
+		params["keep"] = keep
+	}
+
+	return params, nil
+}
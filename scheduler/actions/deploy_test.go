@@ -0,0 +1,313 @@
+package actions
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tobg/scheduler/models"
+)
+
+func TestDeploy(t *testing.T) {
+	tests := map[string]struct {
+		artifact func(t *testing.T, dir string) string
+		wantFile string
+	}{
+		"nominal, directory artifact": {
+			artifact: func(t *testing.T, dir string) string {
+				src := filepath.Join(dir, "build")
+				require.NoError(t, os.MkdirAll(filepath.Join(src, "bin"), 0o755))
+				require.NoError(t, os.WriteFile(filepath.Join(src, "bin", "app"), []byte("app"), 0o755))
+				return src
+			},
+			wantFile: filepath.Join("bin", "app"),
+		},
+		"nominal, tar.gz artifact": {
+			artifact: func(t *testing.T, dir string) string {
+				src := filepath.Join(dir, "app.tar.gz")
+				writeTarGz(t, src, "bin/app", "app")
+				return src
+			},
+			wantFile: filepath.Join("bin", "app"),
+		},
+		"nominal, single file artifact": {
+			artifact: func(t *testing.T, dir string) string {
+				src := filepath.Join(dir, "app")
+				require.NoError(t, os.WriteFile(src, []byte("app"), 0o755))
+				return src
+			},
+			wantFile: "app",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			deploymentPath := filepath.Join(dir, "apps", "service")
+
+			outputs, err := Deploy(context.Background(), models.Params{"service": "service", "deployment_path": deploymentPath, "artifact": tt.artifact(t, dir)})
+			require.NoError(t, err)
+
+			current, err := os.Readlink(filepath.Join(deploymentPath, currentLink))
+			require.NoError(t, err)
+			assert.Equal(t, filepath.Base(current), outputs["release"])
+
+			content, err := os.ReadFile(filepath.Join(deploymentPath, currentLink, tt.wantFile))
+			require.NoError(t, err)
+			assert.Equal(t, "app", string(content))
+		})
+	}
+}
+
+func TestDeployKeepReleases(t *testing.T) {
+	dir := t.TempDir()
+	deploymentPath := filepath.Join(dir, "service")
+	src := filepath.Join(dir, "app")
+	require.NoError(t, os.WriteFile(src, []byte("app"), 0o644))
+
+	for i := 0; i < 4; i++ {
+		_, err := Deploy(context.Background(), models.Params{"service": "service", "deployment_path": deploymentPath, "artifact": src, "keep": 2})
+		require.NoError(t, err)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(deploymentPath, releasesDir))
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	current, err := os.Readlink(filepath.Join(deploymentPath, currentLink))
+	require.NoError(t, err)
+	assert.Equal(t, entries[1].Name(), filepath.Base(current))
+}
+
+func TestDeployMissingArtifact(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := Deploy(context.Background(), models.Params{"service": "service", "deployment_path": filepath.Join(dir, "service"), "artifact": filepath.Join(dir, "missing.tar.gz")})
+	assert.Error(t, err)
+
+	_, err = os.Lstat(filepath.Join(dir, "service", currentLink))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func writeTarGz(t *testing.T, path, name, content string) {
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: filepath.Dir(name) + "/", Typeflag: tar.TypeDir, Mode: 0o755}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o755, Size: int64(len(content))}))
+	_, err = tw.Write([]byte(content))
+	require.NoError(t, err)
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+}
+
+// archiveEntry is a file, or a symlink when link is set, of a test archive
+type archiveEntry struct {
+	name    string
+	link    string
+	content string
+}
+
+func writeTar(t *testing.T, path string, entries ...archiveEntry) {
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	tw := tar.NewWriter(f)
+	for _, e := range entries {
+		if e.link != "" {
+			require.NoError(t, tw.WriteHeader(&tar.Header{Name: e.name, Typeflag: tar.TypeSymlink, Linkname: e.link, Mode: 0o777}))
+			continue
+		}
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: e.name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(e.content))}))
+		_, err = tw.Write([]byte(e.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+}
+
+func writeZip(t *testing.T, path string, entries ...archiveEntry) {
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name}
+		content := e.content
+		if e.link != "" {
+			hdr.SetMode(os.ModeSymlink | 0o777)
+			content = e.link
+		} else {
+			hdr.SetMode(0o644)
+		}
+
+		w, err := zw.CreateHeader(hdr)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+}
+
+func TestDeployArchiveSymlinks(t *testing.T) {
+	tests := map[string]struct {
+		archive string
+		entries []archiveEntry
+		wantErr assert.ErrorAssertionFunc
+	}{
+		"nominal, tar symlink inside the release": {
+			archive: "app.tar",
+			entries: []archiveEntry{{name: "bin/app", content: "app"}, {name: "app", link: "bin/app"}},
+			wantErr: assert.NoError,
+		},
+		"nominal, zip symlink inside the release": {
+			archive: "app.zip",
+			entries: []archiveEntry{{name: "bin/app", content: "app"}, {name: "app", link: "bin/../bin/app"}},
+			wantErr: assert.NoError,
+		},
+		"tar absolute symlink, return error": {
+			archive: "app.tar",
+			entries: []archiveEntry{{name: "app", link: "/etc/passwd"}},
+			wantErr: assert.Error,
+		},
+		"tar symlink escaping the release, return error": {
+			archive: "app.tar",
+			entries: []archiveEntry{{name: "bin/out", link: "../../../.."}},
+			wantErr: assert.Error,
+		},
+		"tar file written through a symlink, return error": {
+			archive: "app.tar",
+			entries: []archiveEntry{{name: "bin", link: "."}, {name: "bin/evil", content: "evil"}},
+			wantErr: assert.Error,
+		},
+		"tar entry escaping the release, return error": {
+			archive: "app.tar",
+			entries: []archiveEntry{{name: "../../evil", content: "evil"}},
+			wantErr: assert.Error,
+		},
+		"zip absolute symlink, return error": {
+			archive: "app.zip",
+			entries: []archiveEntry{{name: "app", link: "/etc/passwd"}},
+			wantErr: assert.Error,
+		},
+		"zip symlink escaping the release, return error": {
+			archive: "app.zip",
+			entries: []archiveEntry{{name: "out", link: "../.."}},
+			wantErr: assert.Error,
+		},
+		"zip file written through a symlink, return error": {
+			archive: "app.zip",
+			entries: []archiveEntry{{name: "bin", link: "."}, {name: "bin/evil", content: "evil"}},
+			wantErr: assert.Error,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			deploymentPath := filepath.Join(dir, "service")
+
+			src := filepath.Join(dir, tt.archive)
+			if filepath.Ext(tt.archive) == ".zip" {
+				writeZip(t, src, tt.entries...)
+			} else {
+				writeTar(t, src, tt.entries...)
+			}
+
+			_, deployErr := Deploy(context.Background(), models.Params{"service": "service", "deployment_path": deploymentPath, "artifact": src})
+			tt.wantErr(t, deployErr)
+
+			_, err := os.Stat(filepath.Join(dir, "evil"))
+			assert.True(t, os.IsNotExist(err))
+
+			if deployErr == nil {
+				content, err := os.ReadFile(filepath.Join(deploymentPath, currentLink, "app"))
+				require.NoError(t, err)
+				assert.Equal(t, "app", string(content))
+			}
+		})
+	}
+}
+
+func TestDeployDirectorySymlinkEscaping(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "build")
+	require.NoError(t, os.Mkdir(src, 0o755))
+	require.NoError(t, os.Symlink("/etc", filepath.Join(src, "etc")))
+
+	_, err := Deploy(context.Background(), models.Params{"service": "service", "deployment_path": filepath.Join(dir, "service"), "artifact": src})
+	assert.Error(t, err)
+
+	_, err = os.Lstat(filepath.Join(dir, "service", currentLink))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDeployCancelled(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "app")
+	require.NoError(t, os.WriteFile(src, []byte("app"), 0o644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Deploy(ctx, models.Params{"service": "service", "deployment_path": filepath.Join(dir, "service"), "artifact": src})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = os.Lstat(filepath.Join(dir, "service", currentLink))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCopyFileCancelled(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "app")
+	require.NoError(t, os.WriteFile(src, []byte("app"), 0o644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := copyFile(ctx, src, filepath.Join(dir, "copy"), 0o644)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestParseDeployArgs(t *testing.T) {
+	tests := map[string]struct {
+		args       []string
+		wantParams models.Params
+		wantErr    assert.ErrorAssertionFunc
+	}{
+		"nominal": {
+			args:       []string{"service", "/home/apps/service", "/tmp/app.tar.gz"},
+			wantParams: models.Params{"service": "service", "deployment_path": "/home/apps/service", "artifact": "/tmp/app.tar.gz"},
+			wantErr:    assert.NoError,
+		},
+		"nominal, releases to keep": {
+			args:       []string{"service", "/home/apps/service", "/tmp/app.tar.gz", "3"},
+			wantParams: models.Params{"service": "service", "deployment_path": "/home/apps/service", "artifact": "/tmp/app.tar.gz", "keep": 3},
+			wantErr:    assert.NoError,
+		},
+		"missing artifact, return error": {
+			args:    []string{"service", "/home/apps/service"},
+			wantErr: assert.Error,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			params, err := ParseDeployArgs(tt.args)
+			tt.wantErr(t, err)
+			assert.Equal(t, tt.wantParams, params)
+		})
+	}
+}
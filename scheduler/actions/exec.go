@@ -0,0 +1,163 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tobg/scheduler/models"
+)
+
+// MaxOutputSize is the number of bytes of stdout and stderr kept from a command
+const MaxOutputSize = 64 << 10
+
+// killDelay is the time left to a cancelled command to release its output
+const killDelay = 5 * time.Second
+
+// ExecSchema describes the params of the exec task
+var ExecSchema = models.ActionSchema{
+	Description: "runs an allowed command and captures its output",
+	Params: []models.ParamSchema{
+		{Name: "dir", Type: models.ParamString, Required: true, Description: "absolute working directory of the command"},
+		{Name: "binary", Type: models.ParamString, Required: true, Description: "absolute path of the binary to run"},
+		{Name: "args", Type: models.ParamStringList, Description: "arguments of the command"},
+	},
+}
+
+// ExecParams are the params of the exec task
+type ExecParams struct {
+	Dir    string   `json:"dir"`
+	Binary string   `json:"binary"`
+	Args   []string `json:"args,omitempty"`
+}
+
+// ExecOptions restricts the commands of the exec task: the binaries it may run
+// and the variables of the scheduler environment the commands see
+type ExecOptions struct {
+	AllowedBinaries []string
+	EnvAllowlist    []string
+}
+
+// NewExec returns the exec task restricted by the options
+func NewExec(opts ExecOptions) models.ActionFunc {
+	return opts.exec
+}
+
+// exec runs a command and captures its output.
+//
+// The binary must be allowed, the command only sees the allowed variables of the
+// scheduler environment and is killed once the context is done.
+// The outputs hold stdout, stderr and the exit code.
+func (o ExecOptions) exec(ctx context.Context, params models.Params) (models.TaskOutputs, error) {
+	var p ExecParams
+	err := params.Decode(&p)
+	if err != nil {
+		return nil, models.Permanent(err)
+	}
+	binary := p.Binary
+
+	err = o.IsAllowedBinary(binary)
+	if err != nil {
+		return nil, models.Permanent(err)
+	}
+
+	var stdout, stderr cappedBuffer
+	cmd := exec.CommandContext(ctx, binary, p.Args...)
+	cmd.Dir = p.Dir
+	cmd.Env = o.env()
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = killDelay
+	setProcessGroup(cmd)
+
+	err = cmd.Run()
+
+	outputs := models.TaskOutputs{
+		"stdout":    stdout.String(),
+		"stderr":    stderr.String(),
+		"exit_code": strconv.Itoa(cmd.ProcessState.ExitCode()),
+	}
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return outputs, ctx.Err()
+		}
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return outputs, fmt.Errorf("command %v failed: %w: %s", binary, err, msg)
+			}
+			return outputs, fmt.Errorf("command %v failed: %w", binary, err)
+		}
+
+		// the command could not start, running it again will not help
+		return outputs, models.Permanent(fmt.Errorf("could not run command %v: %w", binary, err))
+	}
+
+	return outputs, nil
+}
+
+// ParseExecArgs converts the legacy args of the exec task:
+// working directory, binary and the arguments of the command
+func ParseExecArgs(args []string) (models.Params, error) {
+	if len(args) < 2 {
+		return nil, errors.New("invalid number of arguments: expected working directory, binary and optional arguments of the command")
+	}
+
+	return models.Params{
+		"dir":    args[0],
+		"binary": args[1],
+		"args":   args[2:],
+	}, nil
+}
+
+// IsAllowedBinary checks that a binary is an absolute path listed in the allowed binaries
+func (o ExecOptions) IsAllowedBinary(binary string) error {
+	if !filepath.IsAbs(binary) {
+		return fmt.Errorf("binary %v must be an absolute path", binary)
+	}
+
+	for _, allowed := range o.AllowedBinaries {
+		if filepath.Clean(binary) == filepath.Clean(allowed) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("binary %v is not allowed", binary)
+}
+
+// env returns the variables of the scheduler environment a command may see
+func (o ExecOptions) env() []string {
+	var env []string
+	for _, name := range o.EnvAllowlist {
+		if value, exists := os.LookupEnv(name); exists {
+			env = append(env, name+"="+value)
+		}
+	}
+
+	return env
+}
+
+// cappedBuffer keeps the first MaxOutputSize bytes written to it
+type cappedBuffer struct {
+	strings.Builder
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if left := MaxOutputSize - b.Len(); left > 0 {
+		if len(p) > left {
+			b.Builder.Write(p[:left])
+		} else {
+			b.Builder.Write(p)
+		}
+	}
+	// the rest of the output is dropped, the command must not fail on it
+	return len(p), nil
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package actions
+
+import "os/exec"
+
+// setProcessGroup only kills the command itself on this platform
+func setProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,76 @@
+package actions
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tobg/scheduler/models"
+)
+
+func TestExec(t *testing.T) {
+	tests := map[string]struct {
+		script      string
+		wantOutputs models.TaskOutputs
+		wantErr     assert.ErrorAssertionFunc
+	}{
+		"nominal, output captured": {
+			script:      "echo hello; echo warning >&2",
+			wantOutputs: models.TaskOutputs{"stdout": "hello\n", "stderr": "warning\n", "exit_code": "0"},
+			wantErr:     assert.NoError,
+		},
+		"nominal, only allowed environment": {
+			script:      `echo "$EXEC_TEST_ALLOWED-$EXEC_TEST_SECRET"`,
+			wantOutputs: models.TaskOutputs{"stdout": "visible-\n", "stderr": "", "exit_code": "0"},
+			wantErr:     assert.NoError,
+		},
+		"nominal, working directory": {
+			script:      "basename $(pwd)",
+			wantOutputs: models.TaskOutputs{"stdout": "work\n", "stderr": "", "exit_code": "0"},
+			wantErr:     assert.NoError,
+		},
+		"failing command, return error": {
+			script:      "echo broken >&2; exit 3",
+			wantOutputs: models.TaskOutputs{"stdout": "", "stderr": "broken\n", "exit_code": "3"},
+			wantErr:     assert.Error,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("EXEC_TEST_ALLOWED", "visible")
+			t.Setenv("EXEC_TEST_SECRET", "hidden")
+
+			dir := filepath.Join(t.TempDir(), "work")
+			require.NoError(t, os.Mkdir(dir, 0o755))
+
+			exec := NewExec(ExecOptions{AllowedBinaries: []string{"/bin/sh"}, EnvAllowlist: []string{"PATH", "EXEC_TEST_ALLOWED"}})
+			outputs, err := exec(context.Background(), models.Params{"dir": dir, "binary": "/bin/sh", "args": []string{"-c", tt.script}})
+			tt.wantErr(t, err)
+			assert.Equal(t, tt.wantOutputs, outputs)
+		})
+	}
+}
+
+func TestExecBinaryNotAllowed(t *testing.T) {
+	exec := NewExec(ExecOptions{AllowedBinaries: []string{"/usr/bin/make"}})
+
+	_, err := exec(context.Background(), models.Params{"dir": t.TempDir(), "binary": "/bin/sh", "args": []string{"-c", "true"}})
+	assert.True(t, models.IsPermanent(err))
+}
+
+func TestExecCancelled(t *testing.T) {
+	exec := NewExec(ExecOptions{AllowedBinaries: []string{"/bin/sh"}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := exec(ctx, models.Params{"dir": t.TempDir(), "binary": "/bin/sh", "args": []string{"-c", "sleep 10"}})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
@@ -0,0 +1,245 @@
+package actions
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// copyDir recursively copies the content of src into dst
+func copyDir(ctx context.Context, src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return writeSymlink(dst, target, link)
+		default:
+			return copyFile(ctx, path, target, info.Mode())
+		}
+	})
+}
+
+// copyFile copies a single regular file
+func copyFile(ctx context.Context, src, dst string, mode fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	return writeFile(ctx, dst, in, mode)
+}
+
+// writeFile writes the content of r to dst, it stops once the context is done
+func writeFile(ctx context.Context, dst string, r io.Reader, mode fs.FileMode) error {
+	err := os.MkdirAll(filepath.Dir(dst), 0o755)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, contextReader{ctx: ctx, r: r})
+	if err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// contextReader fails the reads once its context is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	err := c.ctx.Err()
+	if err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// writeSymlink creates a symlink at target, the link must be relative and stay in dst.
+// The link is created cleaned so that it cannot go up through another symlink.
+func writeSymlink(dst, target, link string) error {
+	link = filepath.Clean(link)
+	if filepath.IsAbs(link) || !within(dst, filepath.Join(filepath.Dir(target), link)) {
+		return fmt.Errorf("invalid symlink %s: %s points outside of the release", target, link)
+	}
+
+	err := os.MkdirAll(filepath.Dir(target), 0o755)
+	if err != nil {
+		return err
+	}
+
+	return os.Symlink(link, target)
+}
+
+// within reports wether path is dst or one of its descendants
+func within(dst, path string) bool {
+	return path == dst || strings.HasPrefix(path, dst+string(os.PathSeparator))
+}
+
+// safeJoin joins an archive entry name to dst and rejects entries escaping dst,
+// either by their name or by going through a symlink previously extracted
+func safeJoin(dst, name string) (string, error) {
+	target := filepath.Join(dst, name)
+	if !within(dst, target) {
+		return "", fmt.Errorf("invalid archive entry: %s", name)
+	}
+
+	for p := target; p != dst; p = filepath.Dir(p) {
+		info, err := os.Lstat(p)
+		if err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("invalid archive entry: %s goes through symlink %s", name, p)
+		}
+	}
+
+	return target, nil
+}
+
+// extractTar extracts a tar archive, optionally gzip compressed, into dst
+func extractTar(ctx context.Context, src, dst string, gzipped bool) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if gzipped {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := safeJoin(dst, hdr.Name)
+		if err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, hdr.FileInfo().Mode().Perm())
+		case tar.TypeReg:
+			err = writeFile(ctx, target, tr, hdr.FileInfo().Mode())
+		case tar.TypeSymlink:
+			err = writeSymlink(dst, target, hdr.Linkname)
+		default:
+			err = fmt.Errorf("unsupported archive entry type for %s", hdr.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// extractZip extracts a zip archive into dst
+func extractZip(ctx context.Context, src, dst string) error {
+	zr, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		target, err := safeJoin(dst, f.Name)
+		if err != nil {
+			return err
+		}
+
+		if f.FileInfo().IsDir() {
+			err := os.MkdirAll(target, f.Mode().Perm())
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		err = extractZipFile(ctx, dst, target, f)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// extractZipFile extracts a regular file or a symlink of a zip archive to target
+func extractZipFile(ctx context.Context, dst, target string, f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if f.Mode()&os.ModeSymlink == 0 {
+		return writeFile(ctx, target, rc, f.Mode())
+	}
+
+	// the content of a symlink entry is its link
+	link, err := io.ReadAll(io.LimitReader(rc, 4096))
+	if err != nil {
+		return err
+	}
+
+	return writeSymlink(dst, target, string(link))
+}
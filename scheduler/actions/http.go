@@ -0,0 +1,280 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tobg/scheduler/models"
+)
+
+// MaxResponseSize is the number of bytes of a response body read for the assertions
+const MaxResponseSize = 1 << 20
+
+// HTTPSchema describes the params of the http task
+var HTTPSchema = models.ActionSchema{
+	Description: "sends an HTTP request and fails when the response does not match the assertions",
+	Params: []models.ParamSchema{
+		{Name: "method", Type: models.ParamString, Required: true, Description: "HTTP method"},
+		{Name: "url", Type: models.ParamString, Required: true, Description: "absolute http or https URL"},
+		{Name: "headers", Type: models.ParamStringMap, Description: "headers of the request"},
+		{Name: "body", Type: models.ParamString, Description: "body of the request"},
+		{Name: "expect_status", Type: models.ParamInteger, Description: "expected status code, any 2xx status when not set"},
+		{Name: "expect_json", Type: models.ParamStringMap, Description: "expected values of the JSON response by dotted path (items.0.name)"},
+		{Name: "expect_body", Type: models.ParamString, Description: "regular expression the response body must match"},
+		{Name: "outputs", Type: models.ParamStringMap, Description: "outputs of the task by dotted path of the JSON response"},
+	},
+}
+
+// HTTPParams are the params of the http task
+type HTTPParams struct {
+	Method       string            `json:"method"`
+	URL          string            `json:"url"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	Body         string            `json:"body,omitempty"`
+	ExpectStatus int               `json:"expect_status,omitempty"`
+	ExpectJSON   map[string]string `json:"expect_json,omitempty"`
+	ExpectBody   string            `json:"expect_body,omitempty"`
+	Outputs      map[string]string `json:"outputs,omitempty"`
+}
+
+// Check returns an error when the params cannot make a valid request
+func (p HTTPParams) Check() error {
+	if !validMethod(strings.ToUpper(p.Method)) {
+		return fmt.Errorf("invalid method: %v", p.Method)
+	}
+
+	u, err := url.Parse(p.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid URL: %v, expected an absolute http or https URL", p.URL)
+	}
+
+	if p.ExpectStatus != 0 && (p.ExpectStatus < 100 || p.ExpectStatus > 599) {
+		return fmt.Errorf("invalid expected status: %v", p.ExpectStatus)
+	}
+
+	_, err = regexp.Compile(p.ExpectBody)
+	if err != nil {
+		return fmt.Errorf("invalid body pattern %v: %w", p.ExpectBody, err)
+	}
+
+	return nil
+}
+
+// ParseHTTPArgs converts the legacy args of the http task: method, URL, then any of
+// header=<name>: <value>, body=<body>, status=<code>, json=<path>=<value> and match=<regexp>
+func ParseHTTPArgs(args []string) (models.Params, error) {
+	if len(args) < 2 {
+		return nil, errors.New("invalid number of arguments: expected method, URL and optional request options and assertions")
+	}
+
+	params := models.Params{
+		"method": args[0],
+		"url":    args[1],
+	}
+	headers := make(map[string]string)
+	expectJSON := make(map[string]string)
+
+	for _, arg := range args[2:] {
+		option, value, found := strings.Cut(arg, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid option: %v, expected <option>=<value>", arg)
+		}
+
+		switch option {
+		case "header":
+			name, v, found := strings.Cut(value, ":")
+			if !found || strings.TrimSpace(name) == "" {
+				return nil, fmt.Errorf("invalid header: %v, expected <name>: <value>", value)
+			}
+			headers[strings.TrimSpace(name)] = strings.TrimSpace(v)
+		case "body":
+			params["body"] = value
+		case "status":
+			code, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid expected status: %v", value)
+			}
+			params["expect_status"] = code
+		case "json":
+			path, v, found := strings.Cut(value, "=")
+			if !found || path == "" {
+				return nil, fmt.Errorf("invalid JSON assertion: %v, expected <path>=<value>", value)
+			}
+			expectJSON[path] = v
+		case "match":
+			params["expect_body"] = value
+		default:
+			return nil, fmt.Errorf("unknown option: %v", option)
+		}
+	}
+
+	if len(headers) > 0 {
+		params["headers"] = headers
+	}
+	if len(expectJSON) > 0 {
+		params["expect_json"] = expectJSON
+	}
+
+	return params, nil
+}
+
+// HTTP sends a request and checks its response.
+// The outputs hold the status code, the response body and the values extracted from the
+// JSON response at the paths of the outputs param.
+func HTTP(ctx context.Context, params models.Params) (models.TaskOutputs, error) {
+	var p HTTPParams
+	err := params.Decode(&p)
+	if err != nil {
+		return nil, models.Permanent(err)
+	}
+
+	err = p.Check()
+	if err != nil {
+		return nil, models.Permanent(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, strings.ToUpper(p.Method), p.URL, strings.NewReader(p.Body))
+	if err != nil {
+		return nil, models.Permanent(fmt.Errorf("could not create request: %w", err))
+	}
+	for name, value := range p.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("could not read response: %w", err)
+	}
+
+	outputs := models.TaskOutputs{
+		"status": strconv.Itoa(resp.StatusCode),
+		"body":   string(body[:min(len(body), MaxOutputSize)]),
+	}
+
+	err = p.check(resp.StatusCode, body)
+	if err != nil {
+		return outputs, err
+	}
+
+	if len(p.Outputs) == 0 {
+		return outputs, nil
+	}
+
+	var doc any
+	err = json.Unmarshal(body, &doc)
+	if err != nil {
+		return outputs, fmt.Errorf("could not decode JSON response: %w", err)
+	}
+
+	for name, path := range p.Outputs {
+		value, err := jsonValue(doc, path)
+		if err != nil {
+			return outputs, err
+		}
+		outputs[name] = value
+	}
+
+	return outputs, nil
+}
+
+// check returns an error when the response does not match the assertions
+func (p HTTPParams) check(status int, body []byte) error {
+	if p.ExpectStatus != 0 && status != p.ExpectStatus {
+		return fmt.Errorf("unexpected status: got %d, expected %d", status, p.ExpectStatus)
+	}
+	if p.ExpectStatus == 0 && (status < 200 || status > 299) {
+		return fmt.Errorf("unexpected status: got %d, expected 2xx", status)
+	}
+
+	// the pattern is checked before sending the request
+	if p.ExpectBody != "" && !regexp.MustCompile(p.ExpectBody).Match(body) {
+		return fmt.Errorf("response body does not match %v", p.ExpectBody)
+	}
+
+	if len(p.ExpectJSON) == 0 {
+		return nil
+	}
+
+	var doc any
+	err := json.Unmarshal(body, &doc)
+	if err != nil {
+		return fmt.Errorf("could not decode JSON response: %w", err)
+	}
+
+	for path, want := range p.ExpectJSON {
+		got, err := jsonValue(doc, path)
+		if err != nil {
+			return err
+		}
+		if got != want {
+			return fmt.Errorf("unexpected value at %v: got %v, expected %v", path, got, want)
+		}
+	}
+
+	return nil
+}
+
+// jsonValue returns the value at a dotted path of a JSON document,
+// array elements are selected by their index (items.0.name)
+func jsonValue(doc any, path string) (string, error) {
+	current := doc
+	for _, key := range strings.Split(path, ".") {
+		switch v := current.(type) {
+		case map[string]any:
+			next, exists := v[key]
+			if !exists {
+				return "", fmt.Errorf("no value at %v", path)
+			}
+			current = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return "", fmt.Errorf("no value at %v", path)
+			}
+			current = v[i]
+		default:
+			return "", fmt.Errorf("no value at %v", path)
+		}
+	}
+
+	switch v := current.(type) {
+	case string:
+		return v, nil
+	case nil:
+		return "null", nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("could not encode value at %v: %w", path, err)
+		}
+		return string(b), nil
+	}
+}
+
+// validMethod returns wether or not the method is a known HTTP method
+func validMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package actions
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tobg/scheduler/models"
+)
+
+func TestHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"status":"ok","checks":[{"name":"db","up":true}],"version":2}`)
+		case "/hook":
+			body, _ := io.ReadAll(r.Body)
+			if r.Method != http.MethodPost || r.Header.Get("X-Token") != "secret" || string(body) != "ping" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := map[string]struct {
+		params      models.Params
+		wantStatus  string
+		wantOutputs map[string]string
+		wantErr     assert.ErrorAssertionFunc
+	}{
+		"nominal, 2xx expected": {
+			params:     models.Params{"method": "GET", "url": srv.URL + "/health"},
+			wantStatus: "200",
+			wantErr:    assert.NoError,
+		},
+		"nominal, headers and body": {
+			params: models.Params{
+				"method":        "post",
+				"url":           srv.URL + "/hook",
+				"headers":       map[string]string{"X-Token": "secret"},
+				"body":          "ping",
+				"expect_status": 202,
+			},
+			wantStatus: "202",
+			wantErr:    assert.NoError,
+		},
+		"nominal, JSON and body assertions": {
+			params: models.Params{
+				"method":      "GET",
+				"url":         srv.URL + "/health",
+				"expect_json": map[string]string{"status": "ok", "checks.0.up": "true", "version": "2"},
+				"expect_body": `"db"`,
+			},
+			wantStatus: "200",
+			wantErr:    assert.NoError,
+		},
+		"nominal, outputs extracted": {
+			params: models.Params{
+				"method":  "GET",
+				"url":     srv.URL + "/health",
+				"outputs": map[string]string{"version": "version", "first_check": "checks.0.name"},
+			},
+			wantStatus:  "200",
+			wantOutputs: map[string]string{"version": "2", "first_check": "db"},
+			wantErr:     assert.NoError,
+		},
+		"unexpected status, return error": {
+			params:     models.Params{"method": "GET", "url": srv.URL + "/missing"},
+			wantStatus: "404",
+			wantErr:    assert.Error,
+		},
+		"unexpected JSON value, return error": {
+			params:     models.Params{"method": "GET", "url": srv.URL + "/health", "expect_json": map[string]string{"status": "down"}},
+			wantStatus: "200",
+			wantErr:    assert.Error,
+		},
+		"missing JSON path, return error": {
+			params:     models.Params{"method": "GET", "url": srv.URL + "/health", "expect_json": map[string]string{"checks.3.name": "db"}},
+			wantStatus: "200",
+			wantErr:    assert.Error,
+		},
+		"body not matching, return error": {
+			params:     models.Params{"method": "GET", "url": srv.URL + "/health", "expect_body": "degraded"},
+			wantStatus: "200",
+			wantErr:    assert.Error,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			outputs, err := HTTP(context.Background(), tt.params)
+			tt.wantErr(t, err)
+			require.NotNil(t, outputs)
+			assert.Equal(t, tt.wantStatus, outputs["status"])
+			for name, value := range tt.wantOutputs {
+				assert.Equal(t, value, outputs[name])
+			}
+		})
+	}
+}
+
+func TestHTTPInvalidArgs(t *testing.T) {
+	_, err := HTTP(context.Background(), models.Params{"method": "GET", "url": "ftp://civic-assistant.fr"})
+	assert.True(t, models.IsPermanent(err))
+}
+
+func TestParseHTTPArgs(t *testing.T) {
+	params, err := ParseHTTPArgs([]string{"POST", "https://civic-assistant.fr/hook", "header=X-Token: secret", "body=ping", "status=202", "json=ok=true", "match=done"})
+	require.NoError(t, err)
+	assert.Equal(t, models.Params{
+		"method":        "POST",
+		"url":           "https://civic-assistant.fr/hook",
+		"headers":       map[string]string{"X-Token": "secret"},
+		"body":          "ping",
+		"expect_status": 202,
+		"expect_json":   map[string]string{"ok": "true"},
+		"expect_body":   "done",
+	}, params)
+
+	_, err = ParseHTTPArgs([]string{"POST", "https://civic-assistant.fr/hook", "retries=3"})
+	assert.Error(t, err)
+}
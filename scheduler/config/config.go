@@ -0,0 +1,333 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Drivers are the supported database engines
+var Drivers = []string{"sqlite", "postgres"}
+
+// JournalModes are the SQLite journal modes the database accepts
+var JournalModes = []string{"delete", "truncate", "persist", "memory", "wal", "off"}
+
+// LogLevels are the accepted log levels, from the most verbose
+var LogLevels = []string{"debug", "info", "warn", "error"}
+
+// Config is the configuration of the service, an ephemeral service keeps
+// the jobs in memory and does not use the database
+type Config struct {
+	Listen    string         `yaml:"listen"`
+	LogLevel  string         `yaml:"log_level"`
+	Ephemeral bool           `yaml:"ephemeral"`
+	DB        DBConfig       `yaml:"db"`
+	Executor  ExecutorConfig `yaml:"executor"`
+	Exec      ExecConfig     `yaml:"exec"`
+}
+
+// DBConfig tells which database to use and how to connect to it, Path and
+// the SQLite settings apply to sqlite and URL to postgres
+type DBConfig struct {
+	Driver      string        `yaml:"driver"`
+	Path        string        `yaml:"path"`
+	URL         string        `yaml:"url"`
+	JournalMode string        `yaml:"journal_mode"`
+	BusyTimeout time.Duration `yaml:"busy_timeout"`
+}
+
+// ExecutorConfig limits the execution of workflows
+type ExecutorConfig struct {
+	MaxParallelTasks int `yaml:"max_parallel_tasks"`
+}
+
+// ExecConfig restricts the commands of the exec task, only the allowed binaries
+// run and they only see the listed variables of the scheduler environment
+type ExecConfig struct {
+	AllowedBinaries []string `yaml:"allowed_binaries"`
+	EnvAllowlist    []string `yaml:"env_allowlist"`
+}
+
+// Default returns the configuration used when nothing is set
+func Default() Config {
+	return Config{
+		Listen:   ":8080",
+		LogLevel: "info",
+		DB: DBConfig{
+			Driver:      "sqlite",
+			Path:        "../app.db",
+			JournalMode: "delete",
+			BusyTimeout: 5 * time.Second,
+		},
+		Executor: ExecutorConfig{
+			MaxParallelTasks: 4,
+		},
+		Exec: ExecConfig{
+			EnvAllowlist: []string{"PATH", "HOME", "LANG", "TZ"},
+		},
+	}
+}
+
+// Flags are the command line flags of the configuration
+type Flags struct {
+	fs     *flag.FlagSet
+	file   string
+	values Config
+
+	// the lists are comma separated on the command line
+	execAllowedBinaries string
+	execEnvAllowlist    string
+}
+
+// BindFlags defines the flags of the configuration on a flag set
+func BindFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{fs: fs}
+
+	fs.StringVar(&f.file, "config", "", "YAML configuration file (SCHEDULER_CONFIG)")
+	fs.StringVar(&f.values.Listen, "listen", "", "listen address (LISTEN_ADDR, PORT)")
+	fs.StringVar(&f.values.LogLevel, "log-level", "", "log level: "+strings.Join(LogLevels, ", ")+" (LOG_LEVEL)")
+	fs.BoolVar(&f.values.Ephemeral, "ephemeral", false, "keep the jobs in memory, they are lost on exit (EPHEMERAL)")
+	fs.StringVar(&f.values.DB.Driver, "db-driver", "", "database driver: "+strings.Join(Drivers, ", ")+" (DB_DRIVER)")
+	fs.StringVar(&f.values.DB.Path, "db-path", "", "path of the SQLite database (DB_PATH)")
+	fs.StringVar(&f.values.DB.URL, "db-url", "", "connection string of the Postgres database (DB_URL)")
+	fs.StringVar(&f.values.DB.JournalMode, "db-journal-mode", "", "SQLite journal mode: "+strings.Join(JournalModes, ", ")+" (DB_JOURNAL_MODE)")
+	fs.DurationVar(&f.values.DB.BusyTimeout, "db-busy-timeout", 0, "time waited for a locked database (DB_BUSY_TIMEOUT)")
+	fs.IntVar(&f.values.Executor.MaxParallelTasks, "max-parallel-tasks", 0, "tasks of a workflow run in parallel (MAX_PARALLEL_TASKS)")
+	fs.StringVar(&f.execAllowedBinaries, "exec-allowed-binaries", "", "comma separated binaries the exec task may run (EXEC_ALLOWED_BINARIES)")
+	fs.StringVar(&f.execEnvAllowlist, "exec-env-allowlist", "", "comma separated variables the exec commands see (EXEC_ENV_ALLOWLIST)")
+
+	return f
+}
+
+// Load builds the configuration from the defaults, then the configuration file, the
+// environment and the flags set on the command line, each overriding the previous ones.
+// The flags must be parsed before.
+func Load(f *Flags) (Config, error) {
+	c := Default()
+
+	file := os.Getenv("SCHEDULER_CONFIG")
+	if f != nil && f.file != "" {
+		file = f.file
+	}
+	if file != "" {
+		err := c.loadFile(file)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	err := c.loadEnv()
+	if err != nil {
+		return Config{}, err
+	}
+
+	if f != nil {
+		f.apply(&c)
+	}
+
+	err = c.Validate()
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return c, nil
+}
+
+// loadFile reads a YAML configuration file, unknown keys are rejected
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open configuration file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+
+	err = decoder.Decode(c)
+	if err != nil {
+		return fmt.Errorf("could not read configuration file %v: %w", path, err)
+	}
+
+	return nil
+}
+
+// loadEnv reads the environment variables, PORT is kept for the older .env files
+func (c *Config) loadEnv() error {
+	if v := os.Getenv("PORT"); v != "" {
+		c.Listen = v
+	}
+	if v := os.Getenv("LISTEN_ADDR"); v != "" {
+		c.Listen = v
+	}
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		c.LogLevel = v
+	}
+	if v := os.Getenv("EPHEMERAL"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid EPHEMERAL: %v, expected true or false", v)
+		}
+		c.Ephemeral = b
+	}
+	if v := os.Getenv("DB_DRIVER"); v != "" {
+		c.DB.Driver = v
+	}
+	if v := os.Getenv("DB_PATH"); v != "" {
+		c.DB.Path = v
+	}
+	if v := os.Getenv("DB_URL"); v != "" {
+		c.DB.URL = v
+	}
+	if v := os.Getenv("DB_JOURNAL_MODE"); v != "" {
+		c.DB.JournalMode = v
+	}
+	if v := os.Getenv("DB_BUSY_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid DB_BUSY_TIMEOUT: %v, expected a duration such as 5s", v)
+		}
+		c.DB.BusyTimeout = d
+	}
+	if v := os.Getenv("MAX_PARALLEL_TASKS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid MAX_PARALLEL_TASKS: %v, expected a number", v)
+		}
+		c.Executor.MaxParallelTasks = n
+	}
+	if v := os.Getenv("EXEC_ALLOWED_BINARIES"); v != "" {
+		c.Exec.AllowedBinaries = splitList(v)
+	}
+	if v := os.Getenv("EXEC_ENV_ALLOWLIST"); v != "" {
+		c.Exec.EnvAllowlist = splitList(v)
+	}
+
+	return nil
+}
+
+// apply overrides the configuration with the flags set on the command line
+func (f *Flags) apply(c *Config) {
+	f.fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "listen":
+			c.Listen = f.values.Listen
+		case "log-level":
+			c.LogLevel = f.values.LogLevel
+		case "ephemeral":
+			c.Ephemeral = f.values.Ephemeral
+		case "db-driver":
+			c.DB.Driver = f.values.DB.Driver
+		case "db-path":
+			c.DB.Path = f.values.DB.Path
+		case "db-url":
+			c.DB.URL = f.values.DB.URL
+		case "db-journal-mode":
+			c.DB.JournalMode = f.values.DB.JournalMode
+		case "db-busy-timeout":
+			c.DB.BusyTimeout = f.values.DB.BusyTimeout
+		case "max-parallel-tasks":
+			c.Executor.MaxParallelTasks = f.values.Executor.MaxParallelTasks
+		case "exec-allowed-binaries":
+			c.Exec.AllowedBinaries = splitList(f.execAllowedBinaries)
+		case "exec-env-allowlist":
+			c.Exec.EnvAllowlist = splitList(f.execEnvAllowlist)
+		}
+	})
+}
+
+// Validate checks every setting of the configuration
+func (c Config) Validate() error {
+	var errs []error
+
+	_, _, err := net.SplitHostPort(c.Listen)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid listen address: %v, expected [host]:port", c.Listen))
+	}
+
+	if !slices.Contains(LogLevels, strings.ToLower(c.LogLevel)) {
+		errs = append(errs, fmt.Errorf("invalid log level: %v, expected one of %v", c.LogLevel, strings.Join(LogLevels, ", ")))
+	}
+
+	switch {
+	case c.Ephemeral:
+		// the database settings are not used
+	case c.DB.Driver == "sqlite":
+		if c.DB.Path == "" {
+			errs = append(errs, errors.New("missing database path"))
+		}
+
+		if !slices.Contains(JournalModes, strings.ToLower(c.DB.JournalMode)) {
+			errs = append(errs, fmt.Errorf("invalid journal mode: %v, expected one of %v", c.DB.JournalMode, strings.Join(JournalModes, ", ")))
+		}
+
+		if c.DB.BusyTimeout < 0 {
+			errs = append(errs, fmt.Errorf("invalid busy timeout: %v", c.DB.BusyTimeout))
+		}
+	case c.DB.Driver == "postgres":
+		if c.DB.URL == "" {
+			errs = append(errs, errors.New("missing database url, required by the postgres driver"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("invalid database driver: %v, expected one of %v", c.DB.Driver, strings.Join(Drivers, ", ")))
+	}
+
+	if c.Executor.MaxParallelTasks < 1 {
+		errs = append(errs, fmt.Errorf("invalid max parallel tasks: %v, expected at least 1", c.Executor.MaxParallelTasks))
+	}
+
+	for _, binary := range c.Exec.AllowedBinaries {
+		if !filepath.IsAbs(binary) {
+			errs = append(errs, fmt.Errorf("invalid allowed binary: %v, expected an absolute path", binary))
+		}
+	}
+
+	for _, name := range c.Exec.EnvAllowlist {
+		if name == "" || strings.ContainsAny(name, "= ") {
+			errs = append(errs, fmt.Errorf("invalid allowed environment variable: '%v'", name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// splitList splits a comma separated list and drops the empty entries
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// Level returns the slog level of the configured log level
+func (c Config) Level() slog.Level {
+	switch strings.ToLower(c.LogLevel) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// Logger returns a logger writing to w the events of the configured log level and above
+func (c Config) Logger(w io.Writer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: c.Level()}))
+}
@@ -0,0 +1,257 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// clearEnv unsets the variables read by the configuration
+func clearEnv(t *testing.T) {
+	for _, name := range []string{"SCHEDULER_CONFIG", "EPHEMERAL", "PORT", "LISTEN_ADDR", "LOG_LEVEL", "DB_DRIVER", "DB_PATH", "DB_URL", "DB_JOURNAL_MODE", "DB_BUSY_TIMEOUT", "MAX_PARALLEL_TASKS", "EXEC_ALLOWED_BINARIES", "EXEC_ENV_ALLOWLIST"} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(file, []byte("listen: \":9000\"\nlog_level: debug\ndb:\n  path: /var/lib/scheduler.db\n  journal_mode: wal\n  busy_timeout: 2s\nexecutor:\n  max_parallel_tasks: 2\nexec:\n  allowed_binaries: [/usr/bin/make]\n  env_allowlist: [PATH]\n"), 0o600)
+	assert.NoError(t, err)
+
+	unknown := filepath.Join(t.TempDir(), "unknown.yaml")
+	err = os.WriteFile(unknown, []byte("db:\n  name: app\n"), 0o600)
+	assert.NoError(t, err)
+
+	tests := map[string]struct {
+		env     map[string]string
+		args    []string
+		want    func(*Config)
+		wantErr assert.ErrorAssertionFunc
+	}{
+		"nominal, defaults": {
+			want:    func(c *Config) {},
+			wantErr: assert.NoError,
+		},
+		"nominal, file": {
+			args: []string{"--config", file},
+			want: func(c *Config) {
+				c.Listen = ":9000"
+				c.LogLevel = "debug"
+				c.DB = DBConfig{Driver: "sqlite", Path: "/var/lib/scheduler.db", JournalMode: "wal", BusyTimeout: 2 * time.Second}
+				c.Executor.MaxParallelTasks = 2
+				c.Exec = ExecConfig{AllowedBinaries: []string{"/usr/bin/make"}, EnvAllowlist: []string{"PATH"}}
+			},
+			wantErr: assert.NoError,
+		},
+		"nominal, env overrides file": {
+			env:  map[string]string{"SCHEDULER_CONFIG": file, "DB_PATH": "env.db", "PORT": ":7000", "MAX_PARALLEL_TASKS": "8", "EXEC_ALLOWED_BINARIES": "/bin/sh, /usr/bin/make"},
+			args: []string{},
+			want: func(c *Config) {
+				c.Listen = ":7000"
+				c.LogLevel = "debug"
+				c.DB = DBConfig{Driver: "sqlite", Path: "env.db", JournalMode: "wal", BusyTimeout: 2 * time.Second}
+				c.Executor.MaxParallelTasks = 8
+				c.Exec = ExecConfig{AllowedBinaries: []string{"/bin/sh", "/usr/bin/make"}, EnvAllowlist: []string{"PATH"}}
+			},
+			wantErr: assert.NoError,
+		},
+		"nominal, LISTEN_ADDR overrides PORT": {
+			env: map[string]string{"PORT": ":7000", "LISTEN_ADDR": "127.0.0.1:7001"},
+			want: func(c *Config) {
+				c.Listen = "127.0.0.1:7001"
+			},
+			wantErr: assert.NoError,
+		},
+		"nominal, flags override env": {
+			env:  map[string]string{"DB_PATH": "env.db", "DB_BUSY_TIMEOUT": "1s", "EXEC_ALLOWED_BINARIES": "/bin/sh", "EXEC_ENV_ALLOWLIST": "PATH,HOME"},
+			args: []string{"--db-path", "flag.db", "--max-parallel-tasks", "1", "--exec-env-allowlist", "PATH,LANG"},
+			want: func(c *Config) {
+				c.DB.Path = "flag.db"
+				c.DB.BusyTimeout = time.Second
+				c.Executor.MaxParallelTasks = 1
+				c.Exec = ExecConfig{AllowedBinaries: []string{"/bin/sh"}, EnvAllowlist: []string{"PATH", "LANG"}}
+			},
+			wantErr: assert.NoError,
+		},
+		"nominal, postgres": {
+			env:  map[string]string{"DB_DRIVER": "postgres"},
+			args: []string{"--db-url", "postgres://localhost/scheduler"},
+			want: func(c *Config) {
+				c.DB.Driver = "postgres"
+				c.DB.URL = "postgres://localhost/scheduler"
+			},
+			wantErr: assert.NoError,
+		},
+		"nominal, ephemeral ignores database settings": {
+			env:  map[string]string{"EPHEMERAL": "true", "DB_DRIVER": "postgres"},
+			args: []string{},
+			want: func(c *Config) {
+				c.Ephemeral = true
+				c.DB.Driver = "postgres"
+			},
+			wantErr: assert.NoError,
+		},
+		"nominal, ephemeral flag overrides env": {
+			env:     map[string]string{"EPHEMERAL": "true"},
+			args:    []string{"--ephemeral=false"},
+			want:    func(c *Config) {},
+			wantErr: assert.NoError,
+		},
+		"invalid ephemeral env, return error": {
+			env:     map[string]string{"EPHEMERAL": "maybe"},
+			wantErr: assert.Error,
+		},
+		"missing file, return error": {
+			args:    []string{"--config", filepath.Join(t.TempDir(), "missing.yaml")},
+			wantErr: assert.Error,
+		},
+		"unknown key in file, return error": {
+			args:    []string{"--config", unknown},
+			wantErr: assert.Error,
+		},
+		"invalid busy timeout env, return error": {
+			env:     map[string]string{"DB_BUSY_TIMEOUT": "5"},
+			wantErr: assert.Error,
+		},
+		"invalid max parallel tasks env, return error": {
+			env:     map[string]string{"MAX_PARALLEL_TASKS": "many"},
+			wantErr: assert.Error,
+		},
+		"relative allowed binary env, return error": {
+			env:     map[string]string{"EXEC_ALLOWED_BINARIES": "/bin/sh,make"},
+			wantErr: assert.Error,
+		},
+		"invalid journal mode flag, return error": {
+			args:    []string{"--db-journal-mode", "fast"},
+			wantErr: assert.Error,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			flags := BindFlags(fs)
+			assert.NoError(t, fs.Parse(tt.args))
+
+			got, err := Load(flags)
+			tt.wantErr(t, err)
+			if err == nil {
+				want := Default()
+				tt.want(&want)
+				assert.Equal(t, want, got)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := map[string]struct {
+		update  func(*Config)
+		wantErr assert.ErrorAssertionFunc
+	}{
+		"nominal": {
+			update:  func(c *Config) {},
+			wantErr: assert.NoError,
+		},
+		"nominal, host and upper case settings": {
+			update: func(c *Config) {
+				c.Listen = "localhost:8080"
+				c.LogLevel = "WARN"
+				c.DB.JournalMode = "WAL"
+			},
+			wantErr: assert.NoError,
+		},
+		"listen without port, return error": {
+			update:  func(c *Config) { c.Listen = "8080" },
+			wantErr: assert.Error,
+		},
+		"unknown log level, return error": {
+			update:  func(c *Config) { c.LogLevel = "verbose" },
+			wantErr: assert.Error,
+		},
+		"empty db path, return error": {
+			update:  func(c *Config) { c.DB.Path = "" },
+			wantErr: assert.Error,
+		},
+		"unknown driver, return error": {
+			update:  func(c *Config) { c.DB.Driver = "mysql" },
+			wantErr: assert.Error,
+		},
+		"postgres without url, return error": {
+			update:  func(c *Config) { c.DB.Driver = "postgres" },
+			wantErr: assert.Error,
+		},
+		"unknown journal mode, return error": {
+			update:  func(c *Config) { c.DB.JournalMode = "fast" },
+			wantErr: assert.Error,
+		},
+		"negative busy timeout, return error": {
+			update:  func(c *Config) { c.DB.BusyTimeout = -time.Second },
+			wantErr: assert.Error,
+		},
+		"no parallel task, return error": {
+			update:  func(c *Config) { c.Executor.MaxParallelTasks = 0 },
+			wantErr: assert.Error,
+		},
+		"relative allowed binary, return error": {
+			update:  func(c *Config) { c.Exec.AllowedBinaries = []string{"backup.sh"} },
+			wantErr: assert.Error,
+		},
+		"invalid allowed variable, return error": {
+			update:  func(c *Config) { c.Exec.EnvAllowlist = []string{"PATH", "HOME=/root"} },
+			wantErr: assert.Error,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := Default()
+			tt.update(&c)
+			tt.wantErr(t, c.Validate())
+		})
+	}
+}
+
+func TestLogger(t *testing.T) {
+	tests := map[string]struct {
+		level   string
+		emitted []string
+		dropped []string
+	}{
+		"debug": {level: "debug", emitted: []string{"debug event", "info event", "warn event", "error event"}},
+		"info":  {level: "info", emitted: []string{"info event", "warn event", "error event"}, dropped: []string{"debug event"}},
+		"warn":  {level: "warn", emitted: []string{"warn event", "error event"}, dropped: []string{"debug event", "info event"}},
+		"error": {level: "error", emitted: []string{"error event"}, dropped: []string{"debug event", "info event", "warn event"}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := Default()
+			cfg.LogLevel = tt.level
+
+			var buf bytes.Buffer
+			logger := cfg.Logger(&buf)
+			logger.Debug("debug event")
+			logger.Info("info event")
+			logger.Warn("warn event")
+			logger.Error("error event", "error", "boom")
+
+			for _, e := range tt.emitted {
+				assert.Contains(t, buf.String(), e)
+			}
+			for _, d := range tt.dropped {
+				assert.NotContains(t, buf.String(), d)
+			}
+		})
+	}
+}
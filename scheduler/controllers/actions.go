@@ -0,0 +1,20 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/tobg/scheduler/helpers"
+	"github.com/tobg/scheduler/helpers/validations"
+)
+
+// GetActions returns the actions available in workflows and the params they accept
+func (rc *RegisterController) GetActions(w http.ResponseWriter, r *http.Request) {
+	err := validations.IsMethodAllowed(r.Method, http.MethodGet)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusMethodNotAllowed, fmt.Sprintf("invalid method: %v, GET method allowed only", r.Method))
+		return
+	}
+
+	helpers.SendResponseData(w, http.StatusOK, rc.ru.GetActions())
+}
@@ -0,0 +1,270 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/tobg/scheduler/helpers"
+	"github.com/tobg/scheduler/helpers/validations"
+	"github.com/tobg/scheduler/repositories"
+	"github.com/tobg/scheduler/usecases"
+)
+
+// Job dispatches the requests made on a single job according to their method
+func (rc *RegisterController) Job(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		rc.GetJob(w, r)
+	case http.MethodPut:
+		rc.UpdateJob(w, r)
+	case http.MethodPatch:
+		rc.PatchJob(w, r)
+	case http.MethodDelete:
+		rc.DeleteJob(w, r)
+	default:
+		helpers.SendResponseMessage(w, http.StatusMethodNotAllowed, fmt.Sprintf("invalid method: %v, GET, PUT, PATCH and DELETE methods allowed only", r.Method))
+	}
+}
+
+// GetJob returns a single job
+func (rc *RegisterController) GetJob(w http.ResponseWriter, r *http.Request) {
+	id, err := jobID(r)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	job, err := rc.ru.GetJob(id)
+	if err != nil {
+		helpers.SendResponseMessage(w, jobErrorStatus(err), fmt.Errorf("could not retrieve job: %w", err).Error())
+		return
+	}
+
+	helpers.SendResponseData(w, http.StatusOK, job)
+}
+
+// UpdateJob replaces a job with the one in the body
+func (rc *RegisterController) UpdateJob(w http.ResponseWriter, r *http.Request) {
+	id, err := jobID(r)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	job, err := rc.ru.ParseBody(r)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusBadRequest, fmt.Errorf("could not parse body: %w", err).Error())
+		return
+	}
+
+	err = rc.ru.ValidateJob(job)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusBadRequest, fmt.Errorf("could not validate body: %w", err).Error())
+		return
+	}
+
+	job, err = rc.ru.UpdateJob(id, job)
+	if err != nil {
+		helpers.SendResponseMessage(w, jobErrorStatus(err), fmt.Errorf("could not update job: %w", err).Error())
+		return
+	}
+
+	helpers.SendResponseData(w, http.StatusOK, job)
+}
+
+// PatchJob updates the fields of a job present in the body
+func (rc *RegisterController) PatchJob(w http.ResponseWriter, r *http.Request) {
+	id, err := jobID(r)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	job, err := rc.ru.GetJob(id)
+	if err != nil {
+		helpers.SendResponseMessage(w, jobErrorStatus(err), fmt.Errorf("could not retrieve job: %w", err).Error())
+		return
+	}
+
+	job, err = rc.ru.ParsePatch(r, job)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusBadRequest, fmt.Errorf("could not parse body: %w", err).Error())
+		return
+	}
+
+	err = rc.ru.ValidateJob(job)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusBadRequest, fmt.Errorf("could not validate body: %w", err).Error())
+		return
+	}
+
+	job, err = rc.ru.UpdateJob(id, job)
+	if err != nil {
+		helpers.SendResponseMessage(w, jobErrorStatus(err), fmt.Errorf("could not update job: %w", err).Error())
+		return
+	}
+
+	helpers.SendResponseData(w, http.StatusOK, job)
+}
+
+// DeleteJob cancels and removes a job
+func (rc *RegisterController) DeleteJob(w http.ResponseWriter, r *http.Request) {
+	id, err := jobID(r)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = rc.ru.DeleteJob(id)
+	if err != nil {
+		helpers.SendResponseMessage(w, jobErrorStatus(err), fmt.Errorf("could not delete job: %w", err).Error())
+		return
+	}
+
+	helpers.SendResponseMessage(w, http.StatusOK, fmt.Sprintf("job %d deleted", id))
+}
+
+// PauseJob stops the executions of a job until it is resumed
+func (rc *RegisterController) PauseJob(w http.ResponseWriter, r *http.Request) {
+	err := validations.IsMethodAllowed(r.Method, http.MethodPost)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusMethodNotAllowed, fmt.Sprintf("invalid method: %v, POST method allowed only", r.Method))
+		return
+	}
+
+	id, err := jobID(r)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = rc.ru.PauseJob(id)
+	if err != nil {
+		helpers.SendResponseMessage(w, jobErrorStatus(err), fmt.Errorf("could not pause job: %w", err).Error())
+		return
+	}
+
+	helpers.SendResponseMessage(w, http.StatusOK, fmt.Sprintf("job %d paused", id))
+}
+
+// ResumeJob schedules a paused job again
+func (rc *RegisterController) ResumeJob(w http.ResponseWriter, r *http.Request) {
+	err := validations.IsMethodAllowed(r.Method, http.MethodPost)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusMethodNotAllowed, fmt.Sprintf("invalid method: %v, POST method allowed only", r.Method))
+		return
+	}
+
+	id, err := jobID(r)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	job, err := rc.ru.ResumeJob(id)
+	if err != nil {
+		helpers.SendResponseMessage(w, jobErrorStatus(err), fmt.Errorf("could not resume job: %w", err).Error())
+		return
+	}
+
+	helpers.SendResponseData(w, http.StatusOK, job)
+}
+
+// TriggerJob starts a job immediately, outside of its schedule, and answers once the run started
+func (rc *RegisterController) TriggerJob(w http.ResponseWriter, r *http.Request) {
+	err := validations.IsMethodAllowed(r.Method, http.MethodPost)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusMethodNotAllowed, fmt.Sprintf("invalid method: %v, POST method allowed only", r.Method))
+		return
+	}
+
+	id, err := jobID(r)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tr, err := rc.ru.ParseTrigger(r)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusBadRequest, fmt.Errorf("could not parse body: %w", err).Error())
+		return
+	}
+
+	job, err := rc.ru.GetJob(id)
+	if err != nil {
+		helpers.SendResponseMessage(w, jobErrorStatus(err), fmt.Errorf("could not retrieve job: %w", err).Error())
+		return
+	}
+
+	job, err = rc.ru.ApplyTrigger(job, tr)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusBadRequest, fmt.Errorf("could not validate body: %w", err).Error())
+		return
+	}
+
+	run, err := rc.ru.TriggerJob(job, tr)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusInternalServerError, fmt.Errorf("could not trigger job: %w", err).Error())
+		return
+	}
+
+	// the run goes on in the background, its outcome is listed by /jobs/{id}/runs
+	helpers.SendResponseData(w, http.StatusAccepted, run)
+}
+
+// GetNextRuns returns the next fire times of a job, count of them (10 by default)
+func (rc *RegisterController) GetNextRuns(w http.ResponseWriter, r *http.Request) {
+	err := validations.IsMethodAllowed(r.Method, http.MethodGet)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusMethodNotAllowed, fmt.Sprintf("invalid method: %v, GET method allowed only", r.Method))
+		return
+	}
+
+	id, err := jobID(r)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	count := usecases.DefaultPreviewCount
+	if c := r.URL.Query().Get("count"); c != "" {
+		count, err = strconv.Atoi(c)
+		if err != nil {
+			helpers.SendResponseMessage(w, http.StatusBadRequest, fmt.Sprintf("invalid count: %v", c))
+			return
+		}
+	}
+
+	job, err := rc.ru.GetJob(id)
+	if err != nil {
+		helpers.SendResponseMessage(w, jobErrorStatus(err), fmt.Errorf("could not retrieve job: %w", err).Error())
+		return
+	}
+
+	preview, err := rc.ru.GetNextRuns(job, count)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	helpers.SendResponseData(w, http.StatusOK, preview)
+}
+
+// jobID reads the job id from the request path
+func jobID(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid job id: %v", r.PathValue("id"))
+	}
+	return id, nil
+}
+
+// jobErrorStatus returns the status code matching an error on a job
+func jobErrorStatus(err error) int {
+	if errors.Is(err, repositories.ErrJobNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
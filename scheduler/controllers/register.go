@@ -2,8 +2,9 @@ package controllers
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/tobg/scheduler/helpers"
@@ -63,7 +64,7 @@ func (rc *RegisterController) Register(w http.ResponseWriter, r *http.Request) {
 
 	rc.ru.CleanPayload(&job, jobID)
 
-	log.Printf("\n job '%v' received - id %d \n time until starts: %v \n frequency: %v \n", job.Label, jobID, time.Until(job.Schedule), job.Frequency)
+	slog.Info("job received", "job", jobID, "label", job.Label, "starts_in", time.Until(job.Schedule), "frequency", job.Frequency)
 
 	helpers.SendResponseData(w, http.StatusOK, job)
 }
@@ -84,6 +85,44 @@ func (rc *RegisterController) GetJobs(w http.ResponseWriter, r *http.Request) {
 	helpers.SendResponseData(w, http.StatusOK, jobs)
 }
 
+// GetJobRuns returns the execution history of a job, its limit most recent runs (20 by default)
+func (rc *RegisterController) GetJobRuns(w http.ResponseWriter, r *http.Request) {
+	err := validations.IsMethodAllowed(r.Method, http.MethodGet)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusMethodNotAllowed, fmt.Sprintf("invalid method: %v, GET method allowed only", r.Method))
+		return
+	}
+
+	id, err := jobID(r)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit := usecases.DefaultRunsLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		limit, err = strconv.Atoi(l)
+		if err != nil {
+			helpers.SendResponseMessage(w, http.StatusBadRequest, fmt.Sprintf("invalid limit: %v", l))
+			return
+		}
+	}
+
+	err = validations.IsValidRunsLimit(limit, usecases.MaxRunsLimit)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	runs, err := rc.ru.GetJobRuns(id, limit)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusInternalServerError, fmt.Errorf("could not retrieve runs: %w", err).Error())
+		return
+	}
+
+	helpers.SendResponseData(w, http.StatusOK, runs)
+}
+
 func (rc *RegisterController) ReloadJobs() error {
 	err := rc.ru.ReloadJobs()
 	if err != nil {
@@ -0,0 +1,32 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/tobg/scheduler/helpers"
+	"github.com/tobg/scheduler/helpers/validations"
+)
+
+// PreviewSchedule returns the fire times of the schedule in the body without registering a job
+func (rc *RegisterController) PreviewSchedule(w http.ResponseWriter, r *http.Request) {
+	err := validations.IsMethodAllowed(r.Method, http.MethodPost)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusMethodNotAllowed, fmt.Sprintf("invalid method: %v, POST method allowed only", r.Method))
+		return
+	}
+
+	pr, err := rc.ru.ParsePreview(r)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusBadRequest, fmt.Errorf("could not parse body: %w", err).Error())
+		return
+	}
+
+	preview, err := rc.ru.PreviewSchedule(pr)
+	if err != nil {
+		helpers.SendResponseMessage(w, http.StatusBadRequest, fmt.Errorf("could not preview schedule: %w", err).Error())
+		return
+	}
+
+	helpers.SendResponseData(w, http.StatusOK, preview)
+}
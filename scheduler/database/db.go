@@ -2,30 +2,84 @@ package database
 
 import (
 	"database/sql"
-	_ "embed"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
 
+	_ "github.com/jackc/pgx/v5/stdlib"
 	_ "github.com/mattn/go-sqlite3"
 )
 
-//go:embed table_creation.sql
-var table_creation string
+// Options tells which database to use and how to connect to it, Path and the SQLite
+// settings apply to SQLite and URL, a PostgreSQL connection string, to Postgres
+type Options struct {
+	Driver      Driver
+	Path        string
+	URL         string
+	JournalMode string
+	BusyTimeout time.Duration
+}
+
+// dsn returns the data source name of the SQLite driver, foreign keys are enforced on every connection
+func (o Options) dsn() string {
+	params := url.Values{}
+	params.Set("cache", "shared")
+	params.Set("_foreign_keys", "1")
+	if o.JournalMode != "" {
+		params.Set("_journal_mode", strings.ToUpper(o.JournalMode))
+	}
+	if o.BusyTimeout > 0 {
+		params.Set("_busy_timeout", fmt.Sprint(o.BusyTimeout.Milliseconds()))
+	}
+
+	return o.Path + "?" + params.Encode()
+}
+
+// Open opens the database without changing its schema
+func Open(opts Options) (*sql.DB, error) {
+	var db *sql.DB
+	var err error
 
-func InitializeDB() (*sql.DB, error) {
-	dbPath := "../app.db?cache=shared"
-	db, err := sql.Open("sqlite3", dbPath)
+	switch opts.Driver {
+	case SQLite:
+		db, err = sql.Open("sqlite3", opts.dsn())
+	case Postgres:
+		db, err = sql.Open("pgx", opts.URL)
+	default:
+		return nil, fmt.Errorf("unknown database driver: %v", opts.Driver)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not open database: %w", err)
+	}
+
+	return db, nil
+}
+
+// InitializeDB opens the database and applies the pending migrations
+func InitializeDB(opts Options) (*sql.DB, error) {
+	db, err := Open(opts)
 	if err != nil {
 		return nil, fmt.Errorf("could not initialize database: %w", err)
 	}
 
-	_, err = db.Exec(table_creation)
+	_, err = Migrate(db, opts.Driver, false)
 	if err != nil {
 		db.Close()
-		return nil, fmt.Errorf("could not create schema for table issues: %w", err)
+		return nil, fmt.Errorf("could not migrate database: %w", err)
+	}
+
+	orphans, err := CheckOrphans(db, opts.Driver)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if len(orphans) > 0 {
+		slog.Warn("database has orphan rows, run 'scheduler check --clean' to remove them", "orphans", len(orphans))
 	}
 
-	log.Print("initializing database")
+	slog.Info("initializing database", "driver", opts.Driver)
 
 	return db, nil
 }
@@ -0,0 +1,57 @@
+package database
+
+import "fmt"
+
+// Driver is the database engine storing the jobs
+type Driver string
+
+const (
+	SQLite   Driver = "sqlite"
+	Postgres Driver = "postgres"
+)
+
+// dialect holds the statements the migrations need which differ between drivers
+type dialect struct {
+	migrations       string // directory of the embedded migrations
+	createMigrations string
+	recordMigration  string
+	tableExists      string
+	// foreignKeysOff and foreignKeysOn disable the enforcement of foreign keys while migrating
+	foreignKeysOff string
+	foreignKeysOn  string
+}
+
+var dialects = map[Driver]dialect{
+	SQLite: {
+		migrations: "migrations",
+		createMigrations: `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at DATETIME NOT NULL
+);`,
+		recordMigration: "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?);",
+		tableExists:     "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?;",
+		foreignKeysOff:  "PRAGMA foreign_keys = OFF;",
+		foreignKeysOn:   "PRAGMA foreign_keys = ON;",
+	},
+	Postgres: {
+		migrations: "migrations/postgres",
+		createMigrations: `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TIMESTAMPTZ NOT NULL
+);`,
+		recordMigration: "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3);",
+		tableExists:     "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = $1;",
+	},
+}
+
+// dialectOf returns the dialect of a driver
+func dialectOf(driver Driver) (dialect, error) {
+	d, ok := dialects[driver]
+	if !ok {
+		return dialect{}, fmt.Errorf("unknown database driver: %v", driver)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Orphan is a row referencing a row of its parent table which does not exist
+type Orphan struct {
+	Table  string
+	RowID  int64
+	Parent string
+}
+
+// CheckOrphans returns the rows violating a foreign key, such as the tasks of a job deleted
+// while the foreign keys were not enforced. Postgres always enforces them, it has no orphan.
+func CheckOrphans(db *sql.DB, driver Driver) ([]Orphan, error) {
+	if driver != SQLite {
+		return nil, nil
+	}
+
+	return checkOrphans(db)
+}
+
+// CleanOrphans deletes the rows violating a foreign key and returns them
+func CleanOrphans(db *sql.DB, driver Driver) ([]Orphan, error) {
+	if driver != SQLite {
+		return nil, nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("could not begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	orphans, err := checkOrphans(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, o := range orphans {
+		// the table name comes from SQLite, it is safe to quote it in the query
+		_, err = tx.Exec(fmt.Sprintf("DELETE FROM %q WHERE rowid = ?;", o.Table), o.RowID)
+		if err != nil {
+			return nil, fmt.Errorf("could not delete orphan %v %d: %w", o.Table, o.RowID, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("could not commit transaction: %w", err)
+	}
+
+	return orphans, nil
+}
+
+// querier is implemented by sql.DB and sql.Tx
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+func checkOrphans(q querier) ([]Orphan, error) {
+	rows, err := q.Query("PRAGMA foreign_key_check;")
+	if err != nil {
+		return nil, fmt.Errorf("could not check foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	var orphans []Orphan
+	for rows.Next() {
+		var o Orphan
+		var fkid int
+		err := rows.Scan(&o.Table, &o.RowID, &o.Parent, &fkid)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan foreign key violation: %w", err)
+		}
+		orphans = append(orphans, o)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+
+	return orphans, nil
+}
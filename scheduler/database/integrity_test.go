@@ -0,0 +1,70 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForeignKeys(t *testing.T) {
+	db, err := InitializeDB(Options{Driver: SQLite, Path: filepath.Join(t.TempDir(), "app.db")})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec("INSERT INTO jobs (id, occurrences, frequency, label) VALUES (1, -1, 'D', 'backup');")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO workflows (job_id, action) VALUES (1, 'backup'), (1, 'notify');")
+	require.NoError(t, err)
+
+	// a task of a missing job is rejected
+	_, err = db.Exec("INSERT INTO workflows (job_id, action) VALUES (2, 'backup');")
+	assert.Error(t, err)
+
+	// the tasks are deleted with their job
+	_, err = db.Exec("DELETE FROM jobs WHERE id = 1;")
+	require.NoError(t, err)
+
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM workflows;").Scan(&count))
+	assert.Zero(t, count)
+}
+
+func TestOrphans(t *testing.T) {
+	migrations, err := Migrations(SQLite)
+	require.NoError(t, err)
+
+	// a database created before the foreign keys were enforced, the tasks of job 2 were left behind
+	db := openTestDB(t)
+	_, err = db.Exec(migrations[0].SQL +
+		"INSERT INTO jobs (id, occurrences, frequency, label) VALUES (1, -1, 'D', 'backup');\n" +
+		"INSERT INTO workflows (job_id, action) VALUES ('1', 'backup'), ('2', 'backup'), ('2', 'notify');\n")
+	require.NoError(t, err)
+
+	_, err = Migrate(db, SQLite, false)
+	require.NoError(t, err)
+
+	var jobID any
+	require.NoError(t, db.QueryRow("SELECT job_id FROM workflows WHERE action = 'backup' ORDER BY id LIMIT 1;").Scan(&jobID))
+	assert.Equal(t, int64(1), jobID, "job_id is an integer")
+
+	orphans, err := CheckOrphans(db, SQLite)
+	require.NoError(t, err)
+	assert.Equal(t, []Orphan{
+		{Table: "workflows", RowID: 2, Parent: "jobs"},
+		{Table: "workflows", RowID: 3, Parent: "jobs"},
+	}, orphans)
+
+	cleaned, err := CleanOrphans(db, SQLite)
+	require.NoError(t, err)
+	assert.Equal(t, orphans, cleaned)
+
+	orphans, err = CheckOrphans(db, SQLite)
+	require.NoError(t, err)
+	assert.Empty(t, orphans)
+
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM workflows;").Scan(&count))
+	assert.Equal(t, 1, count, "the tasks of existing jobs are kept")
+}
@@ -0,0 +1,264 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql migrations/postgres/*.sql
+var migrationFiles embed.FS
+
+// Migration is a forward change of the schema, migrations are applied once in version order
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// MigrationStatus tells wether a migration is applied to a database, and when
+type MigrationStatus struct {
+	Migration
+	AppliedAt time.Time
+}
+
+// Applied returns wether or not the migration is applied
+func (s MigrationStatus) Applied() bool {
+	return !s.AppliedAt.IsZero()
+}
+
+// Migrations returns the embedded migrations of a driver, named <version>_<name>.sql, in version order
+func Migrations(driver Driver) ([]Migration, error) {
+	d, err := dialectOf(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := fs.Glob(migrationFiles, path.Join(d.migrations, "*.sql"))
+	if err != nil {
+		return nil, fmt.Errorf("could not list migrations: %w", err)
+	}
+
+	migrations := make([]Migration, 0, len(files))
+	versions := make(map[int]string, len(files))
+
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".sql")
+		v, label, found := strings.Cut(name, "_")
+		version, err := strconv.Atoi(v)
+		if !found || err != nil || version < 1 {
+			return nil, fmt.Errorf("invalid migration name: %v, expected <version>_<name>.sql", file)
+		}
+		if other, exists := versions[version]; exists {
+			return nil, fmt.Errorf("migrations %v and %v share version %d", other, name, version)
+		}
+		versions[version] = name
+
+		content, err := migrationFiles.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("could not read migration %v: %w", file, err)
+		}
+
+		migrations = append(migrations, Migration{Version: version, Name: label, SQL: string(content)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Status returns every migration and when it was applied to the database
+func Status(db *sql.DB, driver Driver) ([]MigrationStatus, error) {
+	migrations, err := Migrations(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedMigrations(db, dialects[driver])
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status = append(status, MigrationStatus{Migration: m, AppliedAt: applied[m.Version]})
+	}
+
+	return status, nil
+}
+
+// Migrate applies the pending migrations, each one in its own transaction, and returns them.
+// A dry run returns the pending migrations without applying them.
+//
+// A SQLite database created before the migrations has tables but no schema_migrations table,
+// the columns its version already had are kept when the migrations add them.
+func Migrate(db *sql.DB, driver Driver, dryRun bool) ([]Migration, error) {
+	status, err := Status(db, driver)
+	if err != nil {
+		return nil, err
+	}
+	d := dialects[driver]
+
+	var pending []Migration
+	for _, s := range status {
+		if !s.Applied() {
+			pending = append(pending, s.Migration)
+		}
+	}
+
+	if dryRun || len(pending) == 0 {
+		return pending, nil
+	}
+
+	legacy, err := isLegacy(db, d)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(d.createMigrations)
+	if err != nil {
+		return nil, fmt.Errorf("could not create schema_migrations table: %w", err)
+	}
+
+	// foreign keys are disabled while the migrations rebuild tables, the pragma applies
+	// to a connection and has no effect inside a transaction
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if d.foreignKeysOff != "" {
+		_, err = conn.ExecContext(ctx, d.foreignKeysOff)
+		if err != nil {
+			return nil, fmt.Errorf("could not disable foreign keys: %w", err)
+		}
+		defer conn.ExecContext(ctx, d.foreignKeysOn)
+	}
+
+	for i, m := range pending {
+		err := apply(ctx, conn, d, m, legacy)
+		if err != nil {
+			return pending[:i], err
+		}
+		slog.Info(fmt.Sprintf("migration %04d_%v applied", m.Version, m.Name))
+	}
+
+	return pending, nil
+}
+
+// apply runs the statements of a migration and records it in a single transaction
+func apply(ctx context.Context, conn *sql.Conn, d dialect, m Migration, legacy bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for _, stmt := range statements(m.SQL) {
+		_, err = tx.Exec(stmt)
+		if err != nil && legacy && strings.Contains(err.Error(), "duplicate column name") {
+			// the column was created by the table_creation.sql of an older version
+			err = nil
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("could not apply migration %04d_%v: %w", m.Version, m.Name, err)
+		}
+	}
+
+	_, err = tx.Exec(d.recordMigration, m.Version, m.Name, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("could not record migration %04d_%v: %w", m.Version, m.Name, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("could not commit migration %04d_%v: %w", m.Version, m.Name, err)
+	}
+
+	return nil
+}
+
+// appliedMigrations returns when each applied migration was applied, by version
+func appliedMigrations(db *sql.DB, d dialect) (map[int]time.Time, error) {
+	applied := make(map[int]time.Time)
+
+	exists, err := tableExists(db, d, "schema_migrations")
+	if err != nil || !exists {
+		return applied, err
+	}
+
+	rows, err := db.Query("SELECT version, applied_at FROM schema_migrations;")
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+
+		err := rows.Scan(&version, &appliedAt)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan applied migration: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+
+	return applied, nil
+}
+
+// isLegacy returns wether or not the database was created before the migrations
+func isLegacy(db *sql.DB, d dialect) (bool, error) {
+	migrated, err := tableExists(db, d, "schema_migrations")
+	if err != nil || migrated {
+		return false, err
+	}
+
+	return tableExists(db, d, "jobs")
+}
+
+func tableExists(db *sql.DB, d dialect, name string) (bool, error) {
+	var count int
+
+	err := db.QueryRow(d.tableExists, name).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("could not check table %v: %w", name, err)
+	}
+
+	return count > 0, nil
+}
+
+// statements splits the content of a migration file on the semicolons ending a line
+func statements(content string) []string {
+	var stmts []string
+
+	for _, stmt := range strings.SplitAfter(content, ";\n") {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+	}
+
+	return stmts
+}
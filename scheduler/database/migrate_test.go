@@ -0,0 +1,217 @@
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "app.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func columns(t *testing.T, db *sql.DB, table string) []string {
+	t.Helper()
+
+	rows, err := db.Query("SELECT name FROM pragma_table_info(?);", table)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		require.NoError(t, rows.Scan(&name))
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestMigrations(t *testing.T) {
+	for _, driver := range []Driver{SQLite, Postgres} {
+		migrations, err := Migrations(driver)
+		require.NoError(t, err)
+		require.NotEmpty(t, migrations)
+
+		for i, m := range migrations {
+			assert.Equal(t, i+1, m.Version, "%v migration versions follow each other", driver)
+			assert.NotEmpty(t, statements(m.SQL), "%v migration %v is empty", driver, m.Name)
+		}
+	}
+
+	_, err := Migrations("mysql")
+	assert.Error(t, err)
+}
+
+func TestMigrate(t *testing.T) {
+	migrations, err := Migrations(SQLite)
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		setup       string
+		dryRun      bool
+		wantApplied int
+	}{
+		"new database, apply every migration": {
+			wantApplied: len(migrations),
+		},
+		"dry run, apply nothing": {
+			dryRun:      true,
+			wantApplied: 0,
+		},
+		"database created before migrations, keep existing columns": {
+			// the schema of a version with run history and paused jobs
+			setup: migrations[0].SQL + migrations[1].SQL +
+				"ALTER TABLE jobs ADD COLUMN paused INTEGER NOT NULL DEFAULT 0;\n" +
+				"INSERT INTO jobs (occurrences, frequency, label, paused) VALUES (-1, 'D', 'backup', 1);\n",
+			wantApplied: len(migrations),
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			db := openTestDB(t)
+			if tt.setup != "" {
+				_, err := db.Exec(tt.setup)
+				require.NoError(t, err)
+			}
+
+			got, err := Migrate(db, SQLite, tt.dryRun)
+			require.NoError(t, err)
+			assert.Len(t, got, len(migrations), "pending migrations")
+
+			status, err := Status(db, SQLite)
+			require.NoError(t, err)
+			applied := 0
+			for _, s := range status {
+				if s.Applied() {
+					applied++
+				}
+			}
+			assert.Equal(t, tt.wantApplied, applied)
+
+			if tt.dryRun {
+				assert.NotContains(t, columns(t, db, "jobs"), "timezone")
+				return
+			}
+			assert.Contains(t, columns(t, db, "jobs"), "misfire_policy")
+			assert.Contains(t, columns(t, db, "task_runs"), "phase")
+
+			// applied migrations are not applied again
+			got, err = Migrate(db, SQLite, false)
+			require.NoError(t, err)
+			assert.Empty(t, got)
+		})
+	}
+}
+
+func TestMigrateLegacyData(t *testing.T) {
+	migrations, err := Migrations(SQLite)
+	require.NoError(t, err)
+
+	db := openTestDB(t)
+	_, err = db.Exec(migrations[0].SQL + "INSERT INTO jobs (occurrences, frequency, label) VALUES (-1, 'D', 'backup');\n")
+	require.NoError(t, err)
+
+	_, err = Migrate(db, SQLite, false)
+	require.NoError(t, err)
+
+	var label, concurrency, misfire string
+	err = db.QueryRow("SELECT label, concurrency_policy, misfire_policy FROM jobs;").Scan(&label, &concurrency, &misfire)
+	require.NoError(t, err)
+	assert.Equal(t, "backup", label)
+	assert.Equal(t, "allow", concurrency)
+	assert.Equal(t, "skip", misfire)
+}
+
+func TestMigrateDuplicateRunAttempts(t *testing.T) {
+	db := openTestDB(t)
+
+	// runs of a job created concurrently before attempts were unique
+	_, err := Migrate(db, SQLite, false)
+	require.NoError(t, err)
+	_, err = db.Exec(`DROP INDEX idx_job_runs_job_id_attempt;
+DELETE FROM schema_migrations WHERE name = 'unique_run_attempt';
+INSERT INTO jobs (occurrences, frequency, label) VALUES (-1, 'D', 'backup');
+INSERT INTO job_runs (job_id, attempt, status, started_at) VALUES (1, 1, 'success', '2025-03-01'), (1, 2, 'success', '2025-03-02'), (1, 2, 'success', '2025-03-02'), (1, 3, 'running', '2025-03-03');`)
+	require.NoError(t, err)
+
+	_, err = Migrate(db, SQLite, false)
+	require.NoError(t, err)
+
+	rows, err := db.Query("SELECT attempt FROM job_runs ORDER BY id;")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var attempts []int
+	for rows.Next() {
+		var attempt int
+		require.NoError(t, rows.Scan(&attempt))
+		attempts = append(attempts, attempt)
+	}
+	assert.Equal(t, []int{1, 2, 3, 4}, attempts, "attempts renumbered in the order of the runs")
+
+	_, err = db.Exec("INSERT INTO job_runs (job_id, attempt, status, started_at) VALUES (1, 4, 'running', '2025-03-04');")
+	assert.Error(t, err, "attempts are unique per job")
+}
+
+func TestMigrateRunStartTimesToUTC(t *testing.T) {
+	db := openTestDB(t)
+
+	// runs saved with the offset of the host, 11:00+02:00 started before 10:30Z
+	_, err := Migrate(db, SQLite, false)
+	require.NoError(t, err)
+	_, err = db.Exec(`DROP INDEX idx_job_runs_job_id_started_at;
+DELETE FROM schema_migrations WHERE name = 'job_runs_started_at';
+INSERT INTO jobs (occurrences, frequency, label) VALUES (-1, 'D', 'backup');
+INSERT INTO job_runs (job_id, attempt, status, started_at) VALUES (1, 1, 'success', '2025-03-01T11:00:00.5+02:00'), (1, 2, 'success', '2025-03-01T10:30:00Z');`)
+	require.NoError(t, err)
+
+	_, err = Migrate(db, SQLite, false)
+	require.NoError(t, err)
+
+	rows, err := db.Query("SELECT attempt FROM job_runs ORDER BY started_at DESC;")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var attempts []int
+	for rows.Next() {
+		var attempt int
+		require.NoError(t, rows.Scan(&attempt))
+		attempts = append(attempts, attempt)
+	}
+	assert.Equal(t, []int{2, 1}, attempts, "start times ordered once in UTC")
+
+	var startedAt time.Time
+	require.NoError(t, db.QueryRow("SELECT started_at FROM job_runs WHERE attempt = 1;").Scan(&startedAt))
+	assert.True(t, time.Date(2025, time.March, 1, 9, 0, 0, 5e8, time.UTC).Equal(startedAt), "got %v", startedAt)
+}
+
+func TestMigrateFailureRollsBack(t *testing.T) {
+	db := openTestDB(t)
+
+	// a migration is recorded as pending while its columns exist, applying it again fails
+	_, err := Migrate(db, SQLite, false)
+	require.NoError(t, err)
+	_, err = db.Exec("DELETE FROM schema_migrations WHERE name = 'misfire_policy';")
+	require.NoError(t, err)
+
+	applied, err := Migrate(db, SQLite, false)
+	assert.Error(t, err)
+	assert.Empty(t, applied)
+
+	status, err := Status(db, SQLite)
+	require.NoError(t, err)
+	for _, s := range status {
+		assert.Equal(t, s.Name != "misfire_policy", s.Applied(), "migration %v", s.Name)
+	}
+}
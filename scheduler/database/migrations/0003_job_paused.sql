@@ -0,0 +1 @@
+ALTER TABLE jobs ADD COLUMN paused INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE job_runs ADD COLUMN trigger TEXT NOT NULL DEFAULT 'scheduled';
@@ -0,0 +1 @@
+ALTER TABLE workflows ADD COLUMN retry TEXT;
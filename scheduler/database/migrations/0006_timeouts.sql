@@ -0,0 +1,3 @@
+ALTER TABLE jobs ADD COLUMN timeout INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE workflows ADD COLUMN timeout INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE jobs ADD COLUMN concurrency_policy TEXT NOT NULL DEFAULT 'allow';
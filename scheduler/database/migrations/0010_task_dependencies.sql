@@ -0,0 +1,5 @@
+ALTER TABLE workflows ADD COLUMN task_id TEXT;
+
+ALTER TABLE workflows ADD COLUMN depends_on TEXT;
+
+ALTER TABLE task_runs ADD COLUMN task_id TEXT;
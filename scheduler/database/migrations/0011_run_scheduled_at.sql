@@ -0,0 +1 @@
+ALTER TABLE job_runs ADD COLUMN scheduled_at DATETIME;
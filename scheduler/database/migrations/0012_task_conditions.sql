@@ -0,0 +1,5 @@
+ALTER TABLE workflows ADD COLUMN phase TEXT NOT NULL DEFAULT 'main';
+
+ALTER TABLE workflows ADD COLUMN run_condition TEXT;
+
+ALTER TABLE task_runs ADD COLUMN phase TEXT NOT NULL DEFAULT 'main';
@@ -0,0 +1,3 @@
+ALTER TABLE jobs ADD COLUMN misfire_policy TEXT NOT NULL DEFAULT 'skip';
+
+ALTER TABLE jobs ADD COLUMN misfire_limit INTEGER NOT NULL DEFAULT 0;
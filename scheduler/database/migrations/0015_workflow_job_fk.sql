@@ -0,0 +1,28 @@
+-- workflows.job_id was TEXT, the table is rebuilt to reference jobs(id) with the same type.
+-- Foreign keys are disabled while migrating, the orphan tasks are kept for the checker to report.
+CREATE TABLE workflows_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    job_id INTEGER NOT NULL,
+    task_id TEXT,
+    action TEXT,
+    args TEXT,
+    params TEXT,
+    depends_on TEXT,
+    retry TEXT,
+    timeout INTEGER NOT NULL DEFAULT 0,
+    phase TEXT NOT NULL DEFAULT 'main',
+    run_condition TEXT,
+    FOREIGN KEY (job_id) REFERENCES jobs(id) ON DELETE CASCADE
+);
+
+-- a task with a NULL job_id can not be retrieved, it is not copied
+INSERT INTO workflows_new (id, job_id, task_id, action, args, params, depends_on, retry, timeout, phase, run_condition)
+SELECT id, CAST(job_id AS INTEGER), task_id, action, args, params, depends_on, retry, timeout, phase, run_condition
+FROM workflows
+WHERE job_id IS NOT NULL;
+
+DROP TABLE workflows;
+
+ALTER TABLE workflows_new RENAME TO workflows;
+
+CREATE INDEX IF NOT EXISTS idx_workflows_job_id ON workflows(job_id);
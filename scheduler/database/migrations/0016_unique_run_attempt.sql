@@ -0,0 +1,6 @@
+-- concurrent runs of a job could get the same attempt, the attempts are renumbered
+-- in the order of the runs before being made unique per job
+UPDATE job_runs
+SET attempt = (SELECT COUNT(*) FROM job_runs r WHERE r.job_id = job_runs.job_id AND r.id <= job_runs.id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_job_runs_job_id_attempt ON job_runs(job_id, attempt);
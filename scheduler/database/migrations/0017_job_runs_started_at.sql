@@ -0,0 +1,7 @@
+-- the history of a job is listed from its most recent run, the start times are
+-- compared as text and are now saved in UTC, the older ones are converted
+UPDATE job_runs
+SET started_at = strftime('%Y-%m-%dT%H:%M:%fZ', started_at)
+WHERE strftime('%Y-%m-%dT%H:%M:%fZ', started_at) IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_job_runs_job_id_started_at ON job_runs(job_id, started_at);
@@ -0,0 +1,63 @@
+CREATE TABLE IF NOT EXISTS jobs (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    schedule TIMESTAMPTZ,
+    user_schedule TEXT,
+    timezone TEXT,
+    occurrences INTEGER NOT NULL,
+    frequency TEXT NOT NULL,
+    label TEXT NOT NULL,
+    cron_time TEXT,
+    paused BOOLEAN NOT NULL DEFAULT FALSE,
+    timeout BIGINT NOT NULL DEFAULT 0,
+    concurrency_policy TEXT NOT NULL DEFAULT 'allow',
+    misfire_policy TEXT NOT NULL DEFAULT 'skip',
+    misfire_limit INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS workflows (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    job_id BIGINT NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    task_id TEXT,
+    action TEXT,
+    args TEXT,
+    params TEXT,
+    depends_on TEXT,
+    retry TEXT,
+    timeout BIGINT NOT NULL DEFAULT 0,
+    phase TEXT NOT NULL DEFAULT 'main',
+    run_condition TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_workflows_job_id ON workflows(job_id);
+
+CREATE TABLE IF NOT EXISTS job_runs (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    job_id BIGINT NOT NULL,
+    attempt INTEGER NOT NULL,
+    trigger TEXT NOT NULL DEFAULT 'scheduled',
+    status TEXT NOT NULL,
+    error TEXT,
+    scheduled_at TIMESTAMPTZ,
+    started_at TIMESTAMPTZ NOT NULL,
+    ended_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_runs_job_id ON job_runs(job_id);
+
+CREATE TABLE IF NOT EXISTS task_runs (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    run_id BIGINT NOT NULL REFERENCES job_runs(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    phase TEXT NOT NULL DEFAULT 'main',
+    task_id TEXT,
+    action TEXT NOT NULL,
+    attempt INTEGER NOT NULL,
+    status TEXT NOT NULL,
+    error TEXT,
+    outputs TEXT,
+    started_at TIMESTAMPTZ,
+    ended_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_runs_run_id ON task_runs(run_id);
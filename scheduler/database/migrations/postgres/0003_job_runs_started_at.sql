@@ -0,0 +1,2 @@
+-- the history of a job is listed from its most recent run
+CREATE INDEX IF NOT EXISTS idx_job_runs_job_id_started_at ON job_runs(job_id, started_at);
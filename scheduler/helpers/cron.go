@@ -2,30 +2,53 @@ package helpers
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/robfig/cron"
 	"github.com/tobg/scheduler/models"
 )
 
+// GetCronFrequency sets the cron expression the scheduler fires the job with
 func GetCronFrequency(j *models.Job) error {
-	var cronExpr string
-
-	switch j.Frequency {
-	case "m": // minute
-		cronExpr = "@every 1m"
-	case "H": // hourly
-		cronExpr = "@hourly"
-	case "D": // daily
-		cronExpr = "@daily"
-	case "W": // weekly
-		cronExpr = "@weekly"
-	case "M": // monthly
-		cronExpr = "@monthly"
-	case "Y": // yearly
-		cronExpr = "@yearly"
-	default:
-		return fmt.Errorf("invalid frequency: %s", j.Frequency)
+	expr, err := CronExpression(*j)
+	if err != nil {
+		return err
 	}
 
-	j.CronTime = cronExpr
+	j.CronTime = expr
 	return nil
 }
+
+// ParseCronExpression parses a standard 5 fields cron expression (minute, hour,
+// day of month, month, day of week), a 6 fields one starting with seconds or
+// a descriptor such as "@daily" or "@every 15m"
+func ParseCronExpression(expr string) (cron.Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "@") {
+		return cron.Parse(expr)
+	}
+
+	switch len(strings.Fields(expr)) {
+	case 5:
+		return cron.ParseStandard(expr)
+	case 6:
+		return cron.Parse(expr)
+	default:
+		return nil, fmt.Errorf("expected 5 or 6 fields, found %d: %s", len(strings.Fields(expr)), expr)
+	}
+}
+
+// NormalizeCronExpression validates a cron expression and returns it in the
+// 6 fields format expected by the cron runner
+func NormalizeCronExpression(expr string) (string, error) {
+	_, err := ParseCronExpression(expr)
+	if err != nil {
+		return "", err
+	}
+
+	expr = strings.TrimSpace(expr)
+	if len(strings.Fields(expr)) == 5 {
+		return "0 " + expr, nil
+	}
+	return expr, nil
+}
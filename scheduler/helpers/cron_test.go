@@ -2,12 +2,15 @@ package helpers
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/tobg/scheduler/models"
 )
 
 func TestGetCronFrequency(t *testing.T) {
+	// a friday
+	schedule := time.Date(2024, time.March, 15, 9, 30, 0, 0, time.UTC)
 
 	tests := map[string]struct {
 		j        models.Job
@@ -17,44 +20,91 @@ func TestGetCronFrequency(t *testing.T) {
 		"nominal, every minute": {
 			j: models.Job{
 				Frequency: "m",
+				Schedule:  schedule,
+				Timezone:  "UTC",
 			},
 			wantErr:  assert.NoError,
-			wantCron: "@every 1m",
+			wantCron: "0 * * * * *",
 		},
 		"nominal, every hour": {
 			j: models.Job{
 				Frequency: "H",
+				Schedule:  schedule,
+				Timezone:  "UTC",
 			},
 			wantErr:  assert.NoError,
-			wantCron: "@hourly",
+			wantCron: "0 30 * * * *",
 		},
 		"nominal, every day": {
 			j: models.Job{
 				Frequency: "D",
+				Schedule:  schedule,
+				Timezone:  "UTC",
 			},
 			wantErr:  assert.NoError,
-			wantCron: "@daily",
+			wantCron: "0 30 9 * * *",
 		},
 		"nominal, every week": {
 			j: models.Job{
 				Frequency: "W",
+				Schedule:  schedule,
+				Timezone:  "UTC",
 			},
 			wantErr:  assert.NoError,
-			wantCron: "@weekly",
+			wantCron: "0 30 9 * * 5",
 		},
 		"nominal, every month": {
 			j: models.Job{
 				Frequency: "M",
+				Schedule:  schedule,
+				Timezone:  "UTC",
 			},
 			wantErr:  assert.NoError,
-			wantCron: "@monthly",
+			wantCron: "0 30 9 15 * *",
 		},
 		"nominal, every year": {
 			j: models.Job{
 				Frequency: "Y",
+				Schedule:  schedule,
+				Timezone:  "UTC",
 			},
 			wantErr:  assert.NoError,
-			wantCron: "@yearly",
+			wantCron: "0 30 9 15 3 *",
+		},
+		"nominal, 5 fields cron expression": {
+			j: models.Job{
+				Frequency: "30 2 * * 1-5",
+			},
+			wantErr:  assert.NoError,
+			wantCron: "0 30 2 * * 1-5",
+		},
+		"nominal, 6 fields cron expression": {
+			j: models.Job{
+				Frequency: "15 30 2 * * *",
+			},
+			wantErr:  assert.NoError,
+			wantCron: "15 30 2 * * *",
+		},
+		"nominal, every descriptor": {
+			j: models.Job{
+				Frequency: "@every 15m",
+			},
+			wantErr:  assert.NoError,
+			wantCron: "@every 15m",
+		},
+		"invalid cron expression, return error": {
+			j: models.Job{
+				Frequency: "61 * * * *",
+			},
+			wantErr:  assert.Error,
+			wantCron: "",
+		},
+		"unknown frequency, return error": {
+			j: models.Job{
+				Frequency: "Z",
+			},
+			wantErr:  assert.Error,
+			wantCron: "",
 		},
 	}
 
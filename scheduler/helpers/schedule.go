@@ -0,0 +1,236 @@
+package helpers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron"
+	"github.com/tobg/scheduler/models"
+)
+
+// allHours is the bit set of a cron hour field matching every hour
+const allHours = 1<<24 - 1
+
+// CronExpression returns the 6 fields cron expression of a job frequency. The single letter
+// frequencies repeat the time of the job schedule, read in the job timezone: the minute of
+// the hour, the time of the day, the weekday, the day of the month or the date of the year.
+// Like cron a monthly job on the 31st skips the shorter months and a yearly job on the
+// 29th of February runs on leap years only.
+func CronExpression(j models.Job) (string, error) {
+	location, err := LoadLocation(j.Timezone)
+	if err != nil {
+		return "", err
+	}
+	s := j.Schedule.In(location)
+
+	switch j.Frequency {
+	case "m": // minute
+		return fmt.Sprintf("%d * * * * *", s.Second()), nil
+	case "H": // hourly
+		return fmt.Sprintf("%d %d * * * *", s.Second(), s.Minute()), nil
+	case "D": // daily
+		return fmt.Sprintf("%d %d %d * * *", s.Second(), s.Minute(), s.Hour()), nil
+	case "W": // weekly
+		return fmt.Sprintf("%d %d %d * * %d", s.Second(), s.Minute(), s.Hour(), s.Weekday()), nil
+	case "M": // monthly
+		return fmt.Sprintf("%d %d %d %d * *", s.Second(), s.Minute(), s.Hour(), s.Day()), nil
+	case "Y": // yearly
+		return fmt.Sprintf("%d %d %d %d %d *", s.Second(), s.Minute(), s.Hour(), s.Day(), s.Month()), nil
+	default: // cron expression
+		expr, err := NormalizeCronExpression(j.Frequency)
+		if err != nil {
+			return "", fmt.Errorf("invalid frequency: %s", j.Frequency)
+		}
+		return expr, nil
+	}
+}
+
+// JobSchedule parses the cron expression of a job into a schedule evaluated in the job timezone
+func JobSchedule(expr, timezone string) (cron.Schedule, error) {
+	spec, err := ParseCronExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	location, err := LoadLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	// only the expressions firing at given hours follow the wall clock, the others
+	// repeat every real hour or every delay whatever the DST changes
+	s, isSpec := spec.(*cron.SpecSchedule)
+
+	return zonedSchedule{
+		spec:      spec,
+		location:  location,
+		wallClock: isSpec && s.Hour&allHours != allHours,
+	}, nil
+}
+
+// NextRun returns the first run of a job after the given time: its schedule while it is
+// still ahead, then the next occurrence of its frequency
+func NextRun(j models.Job, after time.Time) (time.Time, error) {
+	if j.Schedule.After(after) {
+		return j.Schedule, nil
+	}
+
+	expr, err := CronExpression(j)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	schedule, err := JobSchedule(expr, j.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	next := schedule.Next(after)
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("frequency %v never fires", j.Frequency)
+	}
+
+	return next, nil
+}
+
+// NextRuns returns the next count runs of a job after the given time, or less if the job
+// has fewer occurrences left, in the job timezone
+func NextRuns(j models.Job, after time.Time, count int) ([]time.Time, error) {
+	location, err := LoadLocation(j.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	if j.IsOneTime {
+		count = min(count, 1)
+	} else if j.Occurrences > 0 {
+		count = min(count, j.Occurrences)
+	}
+
+	runs := make([]time.Time, 0, count)
+	for t := after; len(runs) < count; {
+		next, err := NextRun(j, t)
+		if err != nil {
+			return nil, err
+		}
+
+		runs = append(runs, next.In(location))
+		t = next
+	}
+
+	return runs, nil
+}
+
+// MissedRuns returns the occurrences of a job after since and up to until, keeping the
+// last limit ones in chronological order. A zero since starts from the job schedule.
+func MissedRuns(j models.Job, since, until time.Time, limit int) ([]time.Time, error) {
+	if limit < 1 {
+		return nil, nil
+	}
+
+	expr, err := CronExpression(j)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule, err := JobSchedule(expr, j.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	// the job schedule is the first occurrence, the frequency follows it
+	var first []time.Time
+	if j.Schedule.After(since) {
+		if j.Schedule.After(until) {
+			return nil, nil
+		}
+		first = append(first, j.Schedule)
+		since = j.Schedule
+	}
+
+	// only the last limit occurrences are kept, rather than walking every occurrence since
+	// a long time the walk starts before until and goes back further while they are fewer
+	for window := time.Minute; ; {
+		from := until.Add(-window)
+		if window >= until.Sub(since) {
+			from = since
+		}
+
+		missed, fires := occurrences(schedule, from, until, limit)
+		if !fires {
+			return nil, fmt.Errorf("frequency %v never fires", j.Frequency)
+		}
+
+		if from.Equal(since) {
+			missed = append(first, missed...)
+			return missed[max(len(missed)-limit, 0):], nil
+		}
+		if len(missed) == limit {
+			return missed, nil
+		}
+
+		if window > until.Sub(since)/2 {
+			window = until.Sub(since)
+		} else {
+			window *= 2
+		}
+	}
+}
+
+// occurrences returns the last limit activation times of a schedule after from and up to
+// until, and false when the schedule never fires
+func occurrences(schedule cron.Schedule, from, until time.Time, limit int) ([]time.Time, bool) {
+	var runs []time.Time
+
+	for t := from; ; {
+		next := schedule.Next(t)
+		if next.IsZero() {
+			return nil, false
+		}
+		if next.After(until) {
+			return runs, true
+		}
+
+		runs = append(runs, next)
+		if len(runs) > limit {
+			runs = runs[1:]
+		}
+		t = next
+	}
+}
+
+// zonedSchedule evaluates a cron schedule in a location.
+// A wall clock schedule fires once per matching wall clock time: a time skipped when the
+// clock moves forward fires right after the change and a time repeated when it moves
+// backward fires only the first time.
+type zonedSchedule struct {
+	spec      cron.Schedule
+	location  *time.Location
+	wallClock bool
+}
+
+// Next returns the next activation time after t, the zero time if there is none
+func (s zonedSchedule) Next(t time.Time) time.Time {
+	t = t.In(s.location)
+	if !s.wallClock {
+		return s.spec.Next(t)
+	}
+
+	// the spec runs on the wall clock read as UTC, which has no DST change
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	for {
+		wall = s.spec.Next(wall)
+		if wall.IsZero() {
+			return wall
+		}
+
+		next := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, s.location)
+		if next.Hour() != wall.Hour() || next.Minute() != wall.Minute() {
+			// the wall clock time does not exist, the clock moved forward past it
+			_, next = next.ZoneBounds()
+		}
+		if next.After(t) {
+			return next
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robfig/cron"
+	"github.com/stretchr/testify/assert"
+	"github.com/tobg/scheduler/models"
+)
+
+func TestNextRun(t *testing.T) {
+	utc := func(year int, month time.Month, day, hour, min, sec int) time.Time {
+		return time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+	}
+	// a friday
+	schedule := utc(2024, time.March, 15, 9, 30, 0)
+
+	tests := map[string]struct {
+		job     models.Job
+		after   time.Time
+		want    time.Time
+		wantErr assert.ErrorAssertionFunc
+	}{
+		"schedule ahead, return schedule": {
+			job:     models.Job{Frequency: "D", Schedule: schedule, Timezone: "UTC"},
+			after:   utc(2024, time.March, 1, 0, 0, 0),
+			want:    schedule,
+			wantErr: assert.NoError,
+		},
+		"every minute": {
+			job:     models.Job{Frequency: "m", Schedule: schedule, Timezone: "UTC"},
+			after:   utc(2024, time.March, 20, 10, 0, 20),
+			want:    utc(2024, time.March, 20, 10, 1, 0),
+			wantErr: assert.NoError,
+		},
+		"every hour, later in the same hour": {
+			job:     models.Job{Frequency: "H", Schedule: schedule, Timezone: "UTC"},
+			after:   utc(2024, time.March, 20, 12, 10, 0),
+			want:    utc(2024, time.March, 20, 12, 30, 0),
+			wantErr: assert.NoError,
+		},
+		"every hour, next hour": {
+			job:     models.Job{Frequency: "H", Schedule: schedule, Timezone: "UTC"},
+			after:   utc(2024, time.March, 20, 12, 40, 0),
+			want:    utc(2024, time.March, 20, 13, 30, 0),
+			wantErr: assert.NoError,
+		},
+		"every day, later today": {
+			job:     models.Job{Frequency: "D", Schedule: schedule, Timezone: "UTC"},
+			after:   utc(2024, time.March, 20, 8, 0, 0),
+			want:    utc(2024, time.March, 20, 9, 30, 0),
+			wantErr: assert.NoError,
+		},
+		"every day, tomorrow": {
+			job:     models.Job{Frequency: "D", Schedule: schedule, Timezone: "UTC"},
+			after:   utc(2024, time.March, 20, 10, 0, 0),
+			want:    utc(2024, time.March, 21, 9, 30, 0),
+			wantErr: assert.NoError,
+		},
+		"every week, same weekday": {
+			job:     models.Job{Frequency: "W", Schedule: schedule, Timezone: "UTC"},
+			after:   utc(2024, time.March, 18, 10, 0, 0),
+			want:    utc(2024, time.March, 22, 9, 30, 0),
+			wantErr: assert.NoError,
+		},
+		"every month, on the 31st skip shorter months": {
+			job:     models.Job{Frequency: "M", Schedule: utc(2024, time.January, 31, 9, 30, 0), Timezone: "UTC"},
+			after:   utc(2024, time.February, 1, 0, 0, 0),
+			want:    utc(2024, time.March, 31, 9, 30, 0),
+			wantErr: assert.NoError,
+		},
+		"every month, on the 31st after a run": {
+			job:     models.Job{Frequency: "M", Schedule: utc(2024, time.January, 31, 9, 30, 0), Timezone: "UTC"},
+			after:   utc(2024, time.March, 31, 9, 30, 0),
+			want:    utc(2024, time.May, 31, 9, 30, 0),
+			wantErr: assert.NoError,
+		},
+		"every month, on the 29th out of leap years": {
+			job:     models.Job{Frequency: "M", Schedule: utc(2024, time.January, 29, 9, 30, 0), Timezone: "UTC"},
+			after:   utc(2025, time.February, 1, 0, 0, 0),
+			want:    utc(2025, time.March, 29, 9, 30, 0),
+			wantErr: assert.NoError,
+		},
+		"every month, on the 29th in leap years": {
+			job:     models.Job{Frequency: "M", Schedule: utc(2024, time.January, 29, 9, 30, 0), Timezone: "UTC"},
+			after:   utc(2028, time.February, 1, 0, 0, 0),
+			want:    utc(2028, time.February, 29, 9, 30, 0),
+			wantErr: assert.NoError,
+		},
+		"every year, on a leap day": {
+			job:     models.Job{Frequency: "Y", Schedule: utc(2024, time.February, 29, 9, 30, 0), Timezone: "UTC"},
+			after:   utc(2024, time.March, 1, 0, 0, 0),
+			want:    utc(2028, time.February, 29, 9, 30, 0),
+			wantErr: assert.NoError,
+		},
+		"every year, across new year": {
+			job:     models.Job{Frequency: "Y", Schedule: utc(2024, time.December, 31, 23, 59, 0), Timezone: "UTC"},
+			after:   utc(2025, time.January, 1, 0, 0, 0),
+			want:    utc(2025, time.December, 31, 23, 59, 0),
+			wantErr: assert.NoError,
+		},
+		"timezone, wall clock time kept across DST": {
+			// 09:30 in Paris is 08:30 UTC in winter and 07:30 UTC in summer
+			job:     models.Job{Frequency: "D", Schedule: utc(2025, time.March, 1, 8, 30, 0), Timezone: "Europe/Paris"},
+			after:   utc(2025, time.March, 29, 9, 0, 0),
+			want:    utc(2025, time.March, 30, 7, 30, 0),
+			wantErr: assert.NoError,
+		},
+		"DST forward, skipped time runs after the change": {
+			// 02:30 does not exist in New York on 9 March 2025, the clock jumps from 02:00 EST to 03:00 EDT
+			job:     models.Job{Frequency: "D", Schedule: utc(2025, time.March, 1, 7, 30, 0), Timezone: "America/New_York"},
+			after:   utc(2025, time.March, 8, 17, 0, 0),
+			want:    utc(2025, time.March, 9, 7, 0, 0),
+			wantErr: assert.NoError,
+		},
+		"DST forward, next day at the usual time": {
+			job:     models.Job{Frequency: "D", Schedule: utc(2025, time.March, 1, 7, 30, 0), Timezone: "America/New_York"},
+			after:   utc(2025, time.March, 9, 7, 0, 0),
+			want:    utc(2025, time.March, 10, 6, 30, 0),
+			wantErr: assert.NoError,
+		},
+		"DST backward, repeated time runs once": {
+			// 01:30 happens twice in New York on 2 November 2025, at 05:30 and 06:30 UTC
+			job:     models.Job{Frequency: "D", Schedule: utc(2025, time.October, 1, 5, 30, 0), Timezone: "America/New_York"},
+			after:   utc(2025, time.November, 2, 5, 30, 0),
+			want:    utc(2025, time.November, 3, 6, 30, 0),
+			wantErr: assert.NoError,
+		},
+		"DST backward, hourly runs every real hour": {
+			job:     models.Job{Frequency: "H", Schedule: utc(2025, time.October, 1, 5, 30, 0), Timezone: "America/New_York"},
+			after:   utc(2025, time.November, 2, 5, 30, 0),
+			want:    utc(2025, time.November, 2, 6, 30, 0),
+			wantErr: assert.NoError,
+		},
+		"cron expression in timezone": {
+			// 09:00 on weekdays in Tokyo, friday 3 January 2025 10:00 JST is past
+			job:     models.Job{Frequency: "0 9 * * 1-5", Schedule: utc(2025, time.January, 1, 0, 0, 0), Timezone: "Asia/Tokyo"},
+			after:   utc(2025, time.January, 3, 1, 0, 0),
+			want:    utc(2025, time.January, 6, 0, 0, 0),
+			wantErr: assert.NoError,
+		},
+		"invalid timezone, return error": {
+			job:     models.Job{Frequency: "D", Schedule: schedule, Timezone: "Mars/Olympus_Mons"},
+			after:   utc(2024, time.March, 20, 0, 0, 0),
+			wantErr: assert.Error,
+		},
+		"invalid frequency, return error": {
+			job:     models.Job{Frequency: "Z", Schedule: schedule, Timezone: "UTC"},
+			after:   utc(2024, time.March, 20, 0, 0, 0),
+			wantErr: assert.Error,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := NextRun(tt.job, tt.after)
+			tt.wantErr(t, err)
+			if err == nil {
+				assert.True(t, tt.want.Equal(got), "got %v, want %v", got.UTC(), tt.want)
+			}
+		})
+	}
+}
+
+// TestJobScheduleMatchesCron checks that without DST change the schedules fire
+// at the same times as the robfig/cron ones
+func TestJobScheduleMatchesCron(t *testing.T) {
+	expressions := []string{
+		"0 * * * * *",
+		"15 30 * * * *",
+		"0 30 9 * * *",
+		"0 30 9 * * 5",
+		"0 30 9 31 * *",
+		"0 0 0 29 2 *",
+		"0 */15 8-18 * * 1-5",
+		"@daily",
+		"@every 90m",
+	}
+	start := time.Date(2024, time.January, 30, 23, 59, 30, 0, time.UTC)
+
+	for _, expr := range expressions {
+		t.Run(expr, func(t *testing.T) {
+			want, err := cron.Parse(expr)
+			assert.NoError(t, err)
+			got, err := JobSchedule(expr, "UTC")
+			assert.NoError(t, err)
+
+			w, g := start, start
+			for i := 0; i < 50; i++ {
+				w, g = want.Next(w), got.Next(g)
+				if !assert.True(t, w.Equal(g), "occurrence %d: got %v, want %v", i, g, w) {
+					return
+				}
+			}
+		})
+	}
+}
+
+func TestMissedRuns(t *testing.T) {
+	night := func(day int) time.Time {
+		return time.Date(2025, time.March, day, 2, 0, 0, 0, time.UTC)
+	}
+	job := models.Job{Frequency: "D", Schedule: night(1), Timezone: "UTC"}
+
+	tests := map[string]struct {
+		since time.Time
+		until time.Time
+		limit int
+		want  []time.Time
+	}{
+		"nothing missed": {
+			since: night(5),
+			until: night(5).Add(12 * time.Hour),
+			limit: 10,
+			want:  nil,
+		},
+		"nights missed during a restart": {
+			since: night(5),
+			until: night(8).Add(time.Hour),
+			limit: 10,
+			want:  []time.Time{night(6), night(7), night(8)},
+		},
+		"limit, keep the last ones": {
+			since: night(5),
+			until: night(8).Add(time.Hour),
+			limit: 1,
+			want:  []time.Time{night(8)},
+		},
+		"never ran, start from schedule": {
+			since: time.Time{},
+			until: night(2).Add(time.Hour),
+			limit: 10,
+			want:  []time.Time{night(1), night(2)},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := MissedRuns(job, tt.since, tt.until, tt.limit)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMissedRunsOldJob(t *testing.T) {
+	until := time.Date(2025, time.March, 1, 12, 0, 30, 0, time.UTC)
+	job := models.Job{Frequency: "m", Schedule: until.AddDate(-1, 0, 0), Timezone: "UTC"}
+
+	start := time.Now()
+	got, err := MissedRuns(job, time.Time{}, until, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{until.Add(-2 * time.Minute), until.Add(-time.Minute), until}, got)
+
+	// the half a million minutes of the year are not walked one by one
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestNextRuns(t *testing.T) {
+	schedule := time.Date(2025, time.March, 1, 8, 30, 0, 0, time.UTC)
+	after := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		job       models.Job
+		count     int
+		wantCount int
+	}{
+		"recurring job": {
+			job:       models.Job{Frequency: "D", Schedule: schedule, Occurrences: -1, Timezone: "Europe/Paris"},
+			count:     5,
+			wantCount: 5,
+		},
+		"occurrences left": {
+			job:       models.Job{Frequency: "D", Schedule: schedule, Occurrences: 3, Timezone: "Europe/Paris"},
+			count:     5,
+			wantCount: 3,
+		},
+		"one time job": {
+			job:       models.Job{Frequency: "D", Schedule: schedule, Occurrences: 1, IsOneTime: true, Timezone: "Europe/Paris"},
+			count:     5,
+			wantCount: 1,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := NextRuns(tt.job, after, tt.count)
+			assert.NoError(t, err)
+			assert.Len(t, got, tt.wantCount)
+
+			assert.True(t, schedule.Equal(got[0]))
+			for i, run := range got {
+				// 09:30 in Paris, the times are returned in the job timezone
+				assert.Equal(t, "Europe/Paris", run.Location().String())
+				assert.Equal(t, 9, run.Hour())
+				assert.Equal(t, 1+i, run.Day())
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package helpers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tobg/scheduler/models"
+)
+
+// templatePattern matches a template such as {{ tasks.build.outputs.version }}
+var templatePattern = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`)
+
+// RunFields are the fields of the run a template can reference with run.<field>
+var RunFields = []string{"id", "job_id", "attempt", "trigger", "scheduled_time", "started_at", "status", "error"}
+
+// TemplateRef is a parsed template reference, either to an output of a task
+// (tasks.<task id>.outputs.<output>) or to a field of the run (run.<field>)
+type TemplateRef struct {
+	Task     string
+	Output   string
+	RunField string
+}
+
+// ParseTemplateRef parses the reference of a template
+func ParseTemplateRef(ref string) (TemplateRef, error) {
+	parts := strings.Split(ref, ".")
+
+	switch {
+	case len(parts) == 4 && parts[0] == "tasks" && parts[2] == "outputs" && parts[1] != "" && parts[3] != "":
+		return TemplateRef{Task: parts[1], Output: parts[3]}, nil
+	case len(parts) == 2 && parts[0] == "run":
+		for _, field := range RunFields {
+			if parts[1] == field {
+				return TemplateRef{RunField: field}, nil
+			}
+		}
+		return TemplateRef{}, fmt.Errorf("unknown run field: %v, expected one of %v", parts[1], strings.Join(RunFields, ", "))
+	default:
+		return TemplateRef{}, fmt.Errorf("invalid reference: %v, expected tasks.<task id>.outputs.<output> or run.<field>", ref)
+	}
+}
+
+// TemplateRefs returns the references of the templates found in the string values of params
+func TemplateRefs(params models.Params) []string {
+	var refs []string
+
+	walkStrings(params, func(s string) {
+		for _, m := range templatePattern.FindAllStringSubmatch(s, -1) {
+			refs = append(refs, m[1])
+		}
+	})
+
+	return refs
+}
+
+// IsTemplate returns wether or not a string contains a template
+func IsTemplate(s string) bool {
+	return templatePattern.MatchString(s)
+}
+
+// ResolveTemplates returns a copy of params where each template is replaced by the value
+// of its reference, params without templates are returned as is
+func ResolveTemplates(params models.Params, lookup func(ref string) (string, error)) (models.Params, error) {
+	if len(TemplateRefs(params)) == 0 {
+		return params, nil
+	}
+
+	var err error
+	resolve := func(s string) string {
+		return templatePattern.ReplaceAllStringFunc(s, func(template string) string {
+			ref := templatePattern.FindStringSubmatch(template)[1]
+
+			value, lookupErr := lookup(ref)
+			if lookupErr != nil && err == nil {
+				err = fmt.Errorf("could not resolve %v: %w", template, lookupErr)
+			}
+			return value
+		})
+	}
+
+	resolved := make(models.Params, len(params))
+	for k, v := range params {
+		resolved[k] = mapStrings(v, resolve)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resolved, nil
+}
+
+// walkStrings calls fn on every string of a param value
+func walkStrings(v any, fn func(string)) {
+	mapStrings(v, func(s string) string {
+		fn(s)
+		return s
+	})
+}
+
+// mapStrings returns a copy of a param value where every string is replaced by fn(string)
+func mapStrings(v any, fn func(string) string) any {
+	switch v := v.(type) {
+	case string:
+		return fn(v)
+	case []string:
+		list := make([]string, len(v))
+		for i, s := range v {
+			list[i] = fn(s)
+		}
+		return list
+	case []any:
+		list := make([]any, len(v))
+		for i, item := range v {
+			list[i] = mapStrings(item, fn)
+		}
+		return list
+	case map[string]string:
+		m := make(map[string]string, len(v))
+		for k, s := range v {
+			m[k] = fn(s)
+		}
+		return m
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, item := range v {
+			m[k] = mapStrings(item, fn)
+		}
+		return m
+	case models.Params:
+		m := make(map[string]any, len(v))
+		for k, item := range v {
+			m[k] = mapStrings(item, fn)
+		}
+		return m
+	default:
+		return v
+	}
+}
@@ -0,0 +1,97 @@
+package helpers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tobg/scheduler/models"
+)
+
+func TestResolveTemplates(t *testing.T) {
+	values := map[string]string{
+		"tasks.build.outputs.version": "1.4.2",
+		"run.scheduled_time":          "2026-10-17T02:00:00Z",
+	}
+	lookup := func(ref string) (string, error) {
+		v, exists := values[ref]
+		if !exists {
+			return "", errors.New("unknown")
+		}
+		return v, nil
+	}
+
+	tests := map[string]struct {
+		params     models.Params
+		wantParams models.Params
+		wantErr    assert.ErrorAssertionFunc
+	}{
+		"nominal, no template": {
+			params:     models.Params{"keep": 3, "artifact": "/tmp/app.tar.gz"},
+			wantParams: models.Params{"keep": 3, "artifact": "/tmp/app.tar.gz"},
+			wantErr:    assert.NoError,
+		},
+		"nominal, nested templates": {
+			params: models.Params{
+				"artifact": "/tmp/app-{{ tasks.build.outputs.version }}.tar.gz",
+				"args":     []any{"--at", "{{run.scheduled_time}}"},
+				"headers":  map[string]any{"X-Version": "{{ tasks.build.outputs.version }}"},
+				"keep":     float64(3),
+			},
+			wantParams: models.Params{
+				"artifact": "/tmp/app-1.4.2.tar.gz",
+				"args":     []any{"--at", "2026-10-17T02:00:00Z"},
+				"headers":  map[string]any{"X-Version": "1.4.2"},
+				"keep":     float64(3),
+			},
+			wantErr: assert.NoError,
+		},
+		"unknown reference, return error": {
+			params:  models.Params{"artifact": "{{ tasks.test.outputs.version }}"},
+			wantErr: assert.Error,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			params, err := ResolveTemplates(tt.params, lookup)
+			tt.wantErr(t, err)
+			assert.Equal(t, tt.wantParams, params)
+		})
+	}
+}
+
+func TestParseTemplateRef(t *testing.T) {
+	tests := map[string]struct {
+		ref     string
+		wantRef TemplateRef
+		wantErr assert.ErrorAssertionFunc
+	}{
+		"nominal, task output": {
+			ref:     "tasks.build.outputs.version",
+			wantRef: TemplateRef{Task: "build", Output: "version"},
+			wantErr: assert.NoError,
+		},
+		"nominal, run field": {
+			ref:     "run.scheduled_time",
+			wantRef: TemplateRef{RunField: "scheduled_time"},
+			wantErr: assert.NoError,
+		},
+		"unknown run field, return error": {
+			ref:     "run.label",
+			wantErr: assert.Error,
+		},
+		"invalid reference, return error": {
+			ref:     "tasks.build.version",
+			wantErr: assert.Error,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ref, err := ParseTemplateRef(tt.ref)
+			tt.wantErr(t, err)
+			assert.Equal(t, tt.wantRef, ref)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ScheduleLayout is the layout of a user schedule without timezone, "DD-MM-YYYY HH:MM"
+const ScheduleLayout = "02-01-2006 15:04"
+
+// LoadLocation returns the location of an IANA timezone name,
+// a job without timezone runs in the timezone of the server
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone: %v", name)
+	}
+
+	return location, nil
+}
+
+// ParseSchedule parses a user schedule, either in RFC 3339 format which carries its own
+// offset or in DD-MM-YYYY HH:MM format read in the given location
+func ParseSchedule(s string, location *time.Location) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err == nil {
+		return t, nil
+	}
+
+	t, err = time.ParseInLocation(ScheduleLayout, s, location)
+	if err != nil {
+		return time.Time{}, errors.New("invalid schedule format; expected DD-MM-YYYY HH:MM or RFC 3339")
+	}
+
+	return t, nil
+}
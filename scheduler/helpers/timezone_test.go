@@ -0,0 +1,65 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSchedule(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("timezone database not available")
+	}
+
+	tests := map[string]struct {
+		schedule string
+		location *time.Location
+		want     time.Time
+		wantErr  assert.ErrorAssertionFunc
+	}{
+		"nominal, local format in location": {
+			schedule: "15-01-2025 09:30",
+			location: paris,
+			want:     time.Date(2025, time.January, 15, 8, 30, 0, 0, time.UTC),
+			wantErr:  assert.NoError,
+		},
+		"nominal, local format in summer time": {
+			schedule: "15-07-2025 09:30",
+			location: paris,
+			want:     time.Date(2025, time.July, 15, 7, 30, 0, 0, time.UTC),
+			wantErr:  assert.NoError,
+		},
+		"nominal, RFC 3339 ignores location": {
+			schedule: "2025-01-15T09:30:00-05:00",
+			location: paris,
+			want:     time.Date(2025, time.January, 15, 14, 30, 0, 0, time.UTC),
+			wantErr:  assert.NoError,
+		},
+		"invalid format, return error": {
+			schedule: "2025/01/15 09:30",
+			location: paris,
+			wantErr:  assert.Error,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseSchedule(tt.schedule, tt.location)
+			tt.wantErr(t, err)
+			if err == nil {
+				assert.True(t, tt.want.Equal(got), "got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadLocation(t *testing.T) {
+	location, err := LoadLocation("")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Local, location)
+
+	_, err = LoadLocation("Mars/Olympus_Mons")
+	assert.Error(t, err)
+}
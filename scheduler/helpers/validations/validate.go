@@ -3,20 +3,60 @@ package validations
 import (
 	"errors"
 	"fmt"
+	"math"
+	"net/http"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/tobg/scheduler/actions"
+	"github.com/tobg/scheduler/helpers"
 	"github.com/tobg/scheduler/models"
 )
 
-// Tasks represent all single task available for scheduler
+// taskIDPattern matches the ids a task can be referenced with
+var taskIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// weekdays are the days a task condition accepts
+var weekdays = []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+
+// Tasks represent all single task available for scheduler,
+// the exec task runs no binary until ConfigureExec is called
 var Tasks = map[string]models.TaskHandler{
 	"deploy": {
-		Execute: nil,
-		Verify:  validateDeployArgs,
+		Execute:   actions.Deploy,
+		Verify:    validateDeployParams,
+		ParseArgs: actions.ParseDeployArgs,
+		Schema:    actions.DeploySchema,
+	},
+	"exec": execTask(actions.ExecOptions{}),
+	"http": {
+		Execute:   actions.HTTP,
+		Verify:    validateHTTPParams,
+		ParseArgs: actions.ParseHTTPArgs,
+		Schema:    actions.HTTPSchema,
 	},
 }
 
+// ConfigureExec sets the binaries the exec task may run and the variables the commands see
+func ConfigureExec(opts actions.ExecOptions) {
+	Tasks["exec"] = execTask(opts)
+}
+
+// execTask returns the exec task restricted by the options
+func execTask(opts actions.ExecOptions) models.TaskHandler {
+	return models.TaskHandler{
+		Execute: actions.NewExec(opts),
+		Verify: func(params models.Params) error {
+			return validateExecParams(opts, params)
+		},
+		ParseArgs: actions.ParseExecArgs,
+		Schema:    actions.ExecSchema,
+	}
+}
+
 // IsMethodAllowed returns wether or not the method is allowed
 func IsMethodAllowed(rMethod, method string) error {
 	if rMethod == method {
@@ -32,6 +72,7 @@ func IsValidOccurrences(i int) error {
 	return fmt.Errorf("invalid occurence: %v", i)
 }
 
+// IsValidFrequency accepts a single letter frequency or a cron expression
 func IsValidFrequency(f string) error {
 	validFrequencies := map[string]bool{
 		"W": true,
@@ -42,41 +83,425 @@ func IsValidFrequency(f string) error {
 		"Y": true,
 	}
 
-	if !validFrequencies[f] {
-		return fmt.Errorf("invalid frequency: %v", f)
+	if validFrequencies[f] {
+		return nil
+	}
+
+	_, err := helpers.ParseCronExpression(f)
+	if err != nil {
+		return fmt.Errorf("invalid frequency: %v: %w", f, err)
 	}
 
 	return nil
 }
 
+// IsValidAction checks that the task exists and that its params match the task schema
 func IsValidAction(t models.Task) error {
 	action, exists := Tasks[t.Action]
 	if !exists {
 		return fmt.Errorf("task %v does not exist", t.Action)
 	}
 
-	err := action.Verify(t.Args)
+	params, err := TaskParams(t)
 	if err != nil {
 		return fmt.Errorf("could not verify task %v : %w", t.Action, err)
 	}
 
+	err = IsValidParams(action.Schema, params)
+	if err != nil {
+		return fmt.Errorf("could not verify task %v : %w", t.Action, err)
+	}
+
+	// templated values are only known at execution time, Verify skips them
+	// and they are verified once resolved
+	err = action.Verify(params)
+	if err != nil {
+		return fmt.Errorf("could not verify task %v : %w", t.Action, err)
+	}
+
+	return nil
+}
+
+// IsValidWorkflow checks the dependencies of the tasks of a workflow: task ids are unique,
+// dependencies reference existing tasks and do not form a cycle, templates only reference
+// the outputs of tasks that complete before the task starts and the tasks running on
+// failure have a task to watch.
+// The tasks of the previous workflow, the main workflow for the on failure workflow,
+// complete before any task starts and their ids cannot be reused.
+func IsValidWorkflow(tasks []models.Task, previous []models.Task) error {
+	completed := make(map[string]bool, len(previous))
+	for _, t := range previous {
+		if t.ID != "" {
+			completed[t.ID] = true
+		}
+	}
+
+	positions := make(map[string]int, len(tasks))
+	for i, t := range tasks {
+		if t.ID == "" {
+			continue
+		}
+		if !taskIDPattern.MatchString(t.ID) {
+			return fmt.Errorf("invalid task id: %v, expected letters, digits, - and _ only", t.ID)
+		}
+		if _, exists := positions[t.ID]; exists || completed[t.ID] {
+			return fmt.Errorf("duplicate task id: %v", t.ID)
+		}
+		positions[t.ID] = i
+	}
+
+	// pending counts the dependencies of each task left to visit
+	pending := make([]int, len(tasks))
+	dependents := make([][]int, len(tasks))
+	for i, t := range tasks {
+		for _, id := range t.DependsOn {
+			p, exists := positions[id]
+			if !exists {
+				return fmt.Errorf("task %v depends on unknown task %v", taskName(i, t), id)
+			}
+			if p == i {
+				return fmt.Errorf("task %v depends on itself", id)
+			}
+			pending[i]++
+			dependents[p] = append(dependents[p], i)
+		}
+	}
+
+	var ready []int
+	for i := range tasks {
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	visited := 0
+	for len(ready) > 0 {
+		i := ready[0]
+		ready = ready[1:]
+		visited++
+
+		for _, d := range dependents[i] {
+			pending[d]--
+			if pending[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+
+	if visited < len(tasks) {
+		var cycle []string
+		for i, t := range tasks {
+			if pending[i] > 0 {
+				cycle = append(cycle, taskName(i, t))
+			}
+		}
+		return fmt.Errorf("dependency cycle between tasks: %v", strings.Join(cycle, ", "))
+	}
+
+	deps := helpers.Dependencies(tasks)
+	for i, t := range tasks {
+		// a failure condition is met by a failed dependency, without any the task never runs
+		if t.When != nil && t.When.Status == models.ConditionFailure && len(deps[i]) == 0 {
+			return fmt.Errorf("task %v runs on failure but has no upstream task", taskName(i, t))
+		}
+
+		ancestors := helpers.Ancestors(deps, i)
+
+		for _, ref := range helpers.TemplateRefs(t.Params) {
+			r, err := helpers.ParseTemplateRef(ref)
+			if err != nil {
+				return fmt.Errorf("invalid template in task %v: %w", taskName(i, t), err)
+			}
+
+			if r.Task == "" || completed[r.Task] {
+				continue
+			}
+			p, exists := positions[r.Task]
+			if !exists {
+				return fmt.Errorf("invalid template in task %v: unknown task %v", taskName(i, t), r.Task)
+			}
+			if !ancestors[p] {
+				return fmt.Errorf("invalid template in task %v: task %v does not complete before it starts", taskName(i, t), r.Task)
+			}
+		}
+	}
+
 	return nil
 }
 
-func validateDeployArgs(args []string) error {
-	if len(args) != 2 {
-		return errors.New("invalid number of arguments: expected service name and deployment path")
+// taskName names a task of a workflow in the errors, by its id or else by its position
+func taskName(i int, t models.Task) string {
+	if t.ID != "" {
+		return t.ID
+	}
+	return fmt.Sprintf("at position %d", i)
+}
+
+// IsValidCondition checks the condition of a task, a task without condition runs
+// when all its dependencies succeeded
+func IsValidCondition(c *models.Condition) error {
+	if c == nil {
+		return nil
 	}
 
-	serviceName := args[0]
-	deploymentPath := args[1]
+	for _, day := range c.Weekdays {
+		if !slices.Contains(weekdays, strings.ToLower(day)) {
+			return fmt.Errorf("invalid weekday: %v, expected one of %v", day, strings.Join(weekdays, ", "))
+		}
+	}
 
-	// Validate the deployment path format
-	expectedPath := filepath.Join("/home/apps", serviceName)
+	switch c.Status {
+	case "", models.ConditionSuccess, models.ConditionFailure, models.ConditionAlways:
+		return nil
+	default:
+		return fmt.Errorf("invalid condition status: %v, expected success, failure or always", c.Status)
+	}
+}
 
-	// Check if the deployment path is correct
-	if !strings.HasPrefix(deploymentPath, expectedPath) {
-		return fmt.Errorf("invalid deployment path: expected '%s', got '%s'", expectedPath, deploymentPath)
+// TaskParams returns the params of a task, converting its legacy args if it has no params
+func TaskParams(t models.Task) (models.Params, error) {
+	if t.Params != nil || t.Args == nil {
+		return t.Params, nil
+	}
+
+	action, exists := Tasks[t.Action]
+	if !exists {
+		return nil, fmt.Errorf("task %v does not exist", t.Action)
 	}
+
+	if action.ParseArgs == nil {
+		return nil, fmt.Errorf("task %v does not accept args, use params", t.Action)
+	}
+
+	return action.ParseArgs(t.Args)
+}
+
+// IsValidParams checks params against the schema of an action:
+// required params are present, unknown params are rejected and values have the right type
+func IsValidParams(schema models.ActionSchema, params models.Params) error {
+	known := make(map[string]bool, len(schema.Params))
+
+	for _, p := range schema.Params {
+		known[p.Name] = true
+
+		value, exists := params[p.Name]
+		if !exists || value == nil {
+			if p.Required {
+				return fmt.Errorf("missing param: %v", p.Name)
+			}
+			continue
+		}
+
+		if !isParamType(value, p.Type) {
+			return fmt.Errorf("invalid param %v: expected %v", p.Name, p.Type)
+		}
+
+		if p.NoTemplates && len(helpers.TemplateRefs(models.Params{p.Name: value})) > 0 {
+			return fmt.Errorf("invalid param %v: templates are not allowed", p.Name)
+		}
+	}
+
+	for name := range params {
+		if !known[name] {
+			return fmt.Errorf("unknown param: %v", name)
+		}
+	}
+
 	return nil
 }
+
+// isParamType returns wether or not a value, decoded from JSON or built in go, has the given type
+func isParamType(value any, t models.ParamType) bool {
+	switch t {
+	case models.ParamString:
+		_, ok := value.(string)
+		return ok
+	case models.ParamInteger:
+		switch v := value.(type) {
+		case int, int64:
+			return true
+		case float64:
+			return v == math.Trunc(v)
+		}
+		return false
+	case models.ParamBoolean:
+		_, ok := value.(bool)
+		return ok
+	case models.ParamStringList:
+		switch v := value.(type) {
+		case []string:
+			return true
+		case []any:
+			for _, item := range v {
+				if _, ok := item.(string); !ok {
+					return false
+				}
+			}
+			return true
+		}
+		return false
+	case models.ParamStringMap:
+		switch v := value.(type) {
+		case map[string]string:
+			return true
+		case map[string]any:
+			for _, item := range v {
+				if _, ok := item.(string); !ok {
+					return false
+				}
+			}
+			return true
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// IsValidTimeout checks a job or task timeout, zero means no timeout
+func IsValidTimeout(d models.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("invalid timeout: %v", time.Duration(d))
+	}
+	return nil
+}
+
+// IsValidRetryPolicy checks the retry policy of a task, a task without policy is never retried
+func IsValidRetryPolicy(p *models.RetryPolicy) error {
+	if p == nil {
+		return nil
+	}
+
+	if p.MaxAttempts < 1 {
+		return fmt.Errorf("invalid retry max attempts: %v", p.MaxAttempts)
+	}
+
+	if p.InitialDelay < 0 || p.MaxDelay < 0 {
+		return errors.New("invalid retry delay: delays cannot be negative")
+	}
+
+	if p.MaxDelay > 0 && p.MaxDelay < p.InitialDelay {
+		return errors.New("invalid retry delay: max delay is lower than initial delay")
+	}
+
+	if p.Multiplier != 0 && p.Multiplier < 1 {
+		return fmt.Errorf("invalid retry multiplier: %v", p.Multiplier)
+	}
+
+	for _, pattern := range p.RetryOn {
+		_, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid retry_on pattern %v: %w", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// IsValidConcurrencyPolicy checks the concurrency policy of a job, no policy means allow
+func IsValidConcurrencyPolicy(p models.ConcurrencyPolicy) error {
+	switch p {
+	case "", models.ConcurrencyAllow, models.ConcurrencyForbid, models.ConcurrencyReplace:
+		return nil
+	default:
+		return fmt.Errorf("invalid concurrency policy: %v, expected allow, forbid or replace", p)
+	}
+}
+
+// IsValidMisfirePolicy checks the misfire policy of a job and its limit, no policy means skip
+func IsValidMisfirePolicy(p models.MisfirePolicy, limit int) error {
+	switch p {
+	case "", models.MisfireSkip, models.MisfireRunOnce, models.MisfireRunAll:
+	default:
+		return fmt.Errorf("invalid misfire policy: %v, expected skip, run_once or run_all", p)
+	}
+
+	if limit < 0 {
+		return fmt.Errorf("invalid misfire limit: %v", limit)
+	}
+
+	return nil
+}
+
+// IsValidPreviewCount checks the number of fire times asked to a schedule preview
+func IsValidPreviewCount(count, max int) error {
+	if count < 1 || count > max {
+		return fmt.Errorf("invalid count: %v, expected between 1 and %v", count, max)
+	}
+	return nil
+}
+
+// IsValidRunsLimit checks the number of runs asked to a job history
+func IsValidRunsLimit(limit, max int) error {
+	if limit < 1 || limit > max {
+		return fmt.Errorf("invalid limit: %v, expected between 1 and %v", limit, max)
+	}
+	return nil
+}
+
+func validateDeployParams(params models.Params) error {
+	var p actions.DeployParams
+	err := params.Decode(&p)
+	if err != nil {
+		return err
+	}
+
+	// The service is a single directory name under /home/apps
+	if p.Service == "" || p.Service == "." || p.Service == ".." || strings.Contains(p.Service, "/") {
+		return fmt.Errorf("invalid service name: '%s'", p.Service)
+	}
+
+	// Check if the deployment path is the service directory or one of its subdirectories
+	expectedPath := filepath.Join("/home/apps", p.Service)
+	deploymentPath := filepath.Clean(p.DeploymentPath)
+	if deploymentPath != expectedPath && !strings.HasPrefix(deploymentPath, expectedPath+"/") {
+		return fmt.Errorf("invalid deployment path: expected '%s', got '%s'", expectedPath, p.DeploymentPath)
+	}
+
+	if !helpers.IsTemplate(p.Artifact) && !filepath.IsAbs(p.Artifact) {
+		return fmt.Errorf("invalid artifact path: '%s' must be absolute", p.Artifact)
+	}
+
+	if _, exists := params["keep"]; exists && p.Keep < 1 {
+		return fmt.Errorf("invalid number of releases to keep: %v", p.Keep)
+	}
+	return nil
+}
+
+func validateExecParams(opts actions.ExecOptions, params models.Params) error {
+	var p actions.ExecParams
+	err := params.Decode(&p)
+	if err != nil {
+		return err
+	}
+
+	if !helpers.IsTemplate(p.Dir) && !filepath.IsAbs(p.Dir) {
+		return fmt.Errorf("invalid working directory: '%s' must be absolute", p.Dir)
+	}
+
+	if helpers.IsTemplate(p.Binary) {
+		return nil
+	}
+	return opts.IsAllowedBinary(p.Binary)
+}
+
+func validateHTTPParams(params models.Params) error {
+	var p actions.HTTPParams
+	err := params.Decode(&p)
+	if err != nil {
+		return err
+	}
+
+	// templated values are replaced by valid ones so that the other fields are still checked
+	if helpers.IsTemplate(p.Method) {
+		p.Method = http.MethodGet
+	}
+	if helpers.IsTemplate(p.URL) {
+		p.URL = "http://localhost"
+	}
+	if helpers.IsTemplate(p.ExpectBody) {
+		p.ExpectBody = ""
+	}
+
+	return p.Check()
+}
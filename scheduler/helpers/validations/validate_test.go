@@ -3,8 +3,10 @@ package validations
 import (
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/tobg/scheduler/actions"
 	"github.com/tobg/scheduler/models"
 )
 
@@ -82,10 +84,30 @@ func TestIsValidFrequency(t *testing.T) {
 			frequency: "H",
 			wantErr:   assert.NoError,
 		},
+		"nominal cron expression": {
+			frequency: "30 2 * * 1-5",
+			wantErr:   assert.NoError,
+		},
+		"nominal cron expression with seconds": {
+			frequency: "0 */15 * * * *",
+			wantErr:   assert.NoError,
+		},
+		"nominal every descriptor": {
+			frequency: "@every 15m",
+			wantErr:   assert.NoError,
+		},
 		"unknown frequency, return error": {
 			frequency: "Z",
 			wantErr:   assert.Error,
 		},
+		"invalid cron expression, return error": {
+			frequency: "30 25 * * *",
+			wantErr:   assert.Error,
+		},
+		"invalid number of cron fields, return error": {
+			frequency: "* * *",
+			wantErr:   assert.Error,
+		},
 	}
 
 	for name, tt := range tests {
@@ -104,17 +126,80 @@ func TestIsValidAction(t *testing.T) {
 		"nominal": {
 			task: models.Task{
 				Action: "deploy",
-				Args:   []string{"civic-assistant", "/home/apps/civic-assistant"},
+				Args:   []string{"civic-assistant", "/home/apps/civic-assistant", "/tmp/civic-assistant.tar.gz"},
 			},
 			wantErr: assert.NoError,
 		},
 		"nominal, preprod": {
 			task: models.Task{
 				Action: "deploy",
-				Args:   []string{"civic-assistant", "/home/apps/civic-assistant/preprod"},
+				Args:   []string{"civic-assistant", "/home/apps/civic-assistant/preprod", "/tmp/civic-assistant.tar.gz"},
 			},
 			wantErr: assert.NoError,
 		},
+		"nominal, releases to keep": {
+			task: models.Task{
+				Action: "deploy",
+				Args:   []string{"civic-assistant", "/home/apps/civic-assistant", "/tmp/civic-assistant.tar.gz", "3"},
+			},
+			wantErr: assert.NoError,
+		},
+		"deployment path of another service, return error": {
+			task: models.Task{
+				Action: "deploy",
+				Args:   []string{"civic-assistant", "/home/apps/civic-assistant-other", "/tmp/civic-assistant.tar.gz"},
+			},
+			wantErr: assert.Error,
+		},
+		"deployment path escaping the service, return error": {
+			task: models.Task{
+				Action: "deploy",
+				Args:   []string{"civic-assistant", "/home/apps/civic-assistant/../../etc", "/tmp/civic-assistant.tar.gz"},
+			},
+			wantErr: assert.Error,
+		},
+		"service escaping /home/apps, return error": {
+			task: models.Task{
+				Action: "deploy",
+				Args:   []string{"../../etc", "/etc", "/tmp/civic-assistant.tar.gz"},
+			},
+			wantErr: assert.Error,
+		},
+		"service with a slash, return error": {
+			task: models.Task{
+				Action: "deploy",
+				Args:   []string{"civic-assistant/preprod", "/home/apps/civic-assistant/preprod", "/tmp/civic-assistant.tar.gz"},
+			},
+			wantErr: assert.Error,
+		},
+		"dot service, return error": {
+			task: models.Task{
+				Action: "deploy",
+				Args:   []string{".", "/home/apps", "/tmp/civic-assistant.tar.gz"},
+			},
+			wantErr: assert.Error,
+		},
+		"missing artifact, return error": {
+			task: models.Task{
+				Action: "deploy",
+				Args:   []string{"civic-assistant", "/home/apps/civic-assistant"},
+			},
+			wantErr: assert.Error,
+		},
+		"relative artifact, return error": {
+			task: models.Task{
+				Action: "deploy",
+				Args:   []string{"civic-assistant", "/home/apps/civic-assistant", "build/civic-assistant.tar.gz"},
+			},
+			wantErr: assert.Error,
+		},
+		"invalid releases to keep, return error": {
+			task: models.Task{
+				Action: "deploy",
+				Args:   []string{"civic-assistant", "/home/apps/civic-assistant", "/tmp/civic-assistant.tar.gz", "0"},
+			},
+			wantErr: assert.Error,
+		},
 		"unknown task, return error": {
 			task: models.Task{
 				Action: "unknown",
@@ -125,7 +210,7 @@ func TestIsValidAction(t *testing.T) {
 		"invalid args, return error": {
 			task: models.Task{
 				Action: "deploy",
-				Args:   []string{"aze", "aze"},
+				Args:   []string{"aze", "aze", "/tmp/aze.tar.gz"},
 			},
 			wantErr: assert.Error,
 		},
@@ -136,8 +221,153 @@ func TestIsValidAction(t *testing.T) {
 			},
 			wantErr: assert.Error,
 		},
+		"nominal, params": {
+			task: models.Task{
+				Action: "deploy",
+				Params: models.Params{
+					"service":         "civic-assistant",
+					"deployment_path": "/home/apps/civic-assistant",
+					"artifact":        "/tmp/civic-assistant,hotfix.tar.gz",
+					"keep":            float64(3),
+				},
+			},
+			wantErr: assert.NoError,
+		},
+		"params not matching schema, return error": {
+			task: models.Task{
+				Action: "deploy",
+				Params: models.Params{
+					"service":         "civic-assistant",
+					"deployment_path": "/home/apps/civic-assistant",
+					"artifact":        "/tmp/civic-assistant.tar.gz",
+					"keep":            "3",
+				},
+			},
+			wantErr: assert.Error,
+		},
+		"nominal, exec": {
+			task: models.Task{
+				Action: "exec",
+				Args:   []string{"/home/apps/civic-assistant", "/usr/local/bin/backup.sh", "--full"},
+			},
+			wantErr: assert.NoError,
+		},
+		"exec binary not allowed, return error": {
+			task: models.Task{
+				Action: "exec",
+				Args:   []string{"/home/apps/civic-assistant", "/bin/rm", "-rf", "/"},
+			},
+			wantErr: assert.Error,
+		},
+		"exec relative working directory, return error": {
+			task: models.Task{
+				Action: "exec",
+				Args:   []string{"civic-assistant", "/usr/local/bin/backup.sh"},
+			},
+			wantErr: assert.Error,
+		},
+		"exec without binary, return error": {
+			task: models.Task{
+				Action: "exec",
+				Args:   []string{"/home/apps/civic-assistant"},
+			},
+			wantErr: assert.Error,
+		},
+		"nominal, http": {
+			task: models.Task{
+				Action: "http",
+				Args:   []string{"GET", "https://civic-assistant.fr/health", "status=200", "json=status=ok"},
+			},
+			wantErr: assert.NoError,
+		},
+		"http relative URL, return error": {
+			task: models.Task{
+				Action: "http",
+				Args:   []string{"GET", "/health"},
+			},
+			wantErr: assert.Error,
+		},
+		"http unknown option, return error": {
+			task: models.Task{
+				Action: "http",
+				Args:   []string{"POST", "https://civic-assistant.fr/hook", "retries=3"},
+			},
+			wantErr: assert.Error,
+		},
+		"nominal, templated artifact": {
+			task: models.Task{
+				Action: "deploy",
+				Params: models.Params{
+					"service":         "civic-assistant",
+					"deployment_path": "/home/apps/civic-assistant",
+					"artifact":        "{{ tasks.build.outputs.stdout }}",
+				},
+			},
+			wantErr: assert.NoError,
+		},
+		"templated artifact, invalid deployment path, return error": {
+			task: models.Task{
+				Action: "deploy",
+				Params: models.Params{
+					"service":         "civic-assistant",
+					"deployment_path": "/etc",
+					"artifact":        "{{ tasks.build.outputs.stdout }}",
+				},
+			},
+			wantErr: assert.Error,
+		},
+		"templated deployment path, return error": {
+			task: models.Task{
+				Action: "deploy",
+				Params: models.Params{
+					"service":         "civic-assistant",
+					"deployment_path": "/home/apps/civic-assistant/{{ tasks.build.outputs.stdout }}",
+					"artifact":        "/tmp/civic-assistant.tar.gz",
+				},
+			},
+			wantErr: assert.Error,
+		},
+		"templated service, return error": {
+			task: models.Task{
+				Action: "deploy",
+				Params: models.Params{
+					"service":         "{{ tasks.build.outputs.stdout }}",
+					"deployment_path": "/home/apps/civic-assistant",
+					"artifact":        "/tmp/civic-assistant.tar.gz",
+				},
+			},
+			wantErr: assert.Error,
+		},
+		"templated exec args, binary not allowed, return error": {
+			task: models.Task{
+				Action: "exec",
+				Params: models.Params{
+					"dir":    "/home/apps/civic-assistant",
+					"binary": "/bin/rm",
+					"args":   []string{"{{ tasks.build.outputs.stdout }}"},
+				},
+			},
+			wantErr: assert.Error,
+		},
+		"nominal, templated http URL": {
+			task: models.Task{
+				Action: "http",
+				Params: models.Params{"method": "POST", "url": "{{ tasks.discover.outputs.stdout }}", "expect_status": 202},
+			},
+			wantErr: assert.NoError,
+		},
+		"templated http body, invalid URL, return error": {
+			task: models.Task{
+				Action: "http",
+				Params: models.Params{"method": "POST", "url": "/hook", "body": "{{ run.status }}"},
+			},
+			wantErr: assert.Error,
+		},
 	}
 
+	ConfigureExec(actions.ExecOptions{AllowedBinaries: []string{"/usr/local/bin/backup.sh", "/usr/bin/make"}})
+	t.Cleanup(func() { ConfigureExec(actions.ExecOptions{}) })
+
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
 			err := IsValidAction(tt.task)
@@ -145,3 +375,334 @@ func TestIsValidAction(t *testing.T) {
 		})
 	}
 }
+
+func TestIsValidRetryPolicy(t *testing.T) {
+	tests := map[string]struct {
+		policy  *models.RetryPolicy
+		wantErr assert.ErrorAssertionFunc
+	}{
+		"nominal, no policy": {
+			policy:  nil,
+			wantErr: assert.NoError,
+		},
+		"nominal": {
+			policy: &models.RetryPolicy{
+				MaxAttempts:  3,
+				InitialDelay: models.Duration(time.Second),
+				Multiplier:   2,
+				MaxDelay:     models.Duration(time.Minute),
+				RetryOn:      []string{"timeout", "connection (refused|reset)"},
+			},
+			wantErr: assert.NoError,
+		},
+		"no attempt, return error": {
+			policy:  &models.RetryPolicy{MaxAttempts: 0},
+			wantErr: assert.Error,
+		},
+		"max delay lower than initial delay, return error": {
+			policy: &models.RetryPolicy{
+				MaxAttempts:  3,
+				InitialDelay: models.Duration(time.Minute),
+				MaxDelay:     models.Duration(time.Second),
+			},
+			wantErr: assert.Error,
+		},
+		"decreasing multiplier, return error": {
+			policy:  &models.RetryPolicy{MaxAttempts: 3, Multiplier: 0.5},
+			wantErr: assert.Error,
+		},
+		"invalid pattern, return error": {
+			policy:  &models.RetryPolicy{MaxAttempts: 3, RetryOn: []string{"("}},
+			wantErr: assert.Error,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := IsValidRetryPolicy(tt.policy)
+			tt.wantErr(t, err)
+		})
+	}
+}
+
+func TestIsValidConcurrencyPolicy(t *testing.T) {
+	tests := map[string]struct {
+		policy  models.ConcurrencyPolicy
+		wantErr assert.ErrorAssertionFunc
+	}{
+		"nominal, no policy": {
+			policy:  "",
+			wantErr: assert.NoError,
+		},
+		"nominal, forbid": {
+			policy:  models.ConcurrencyForbid,
+			wantErr: assert.NoError,
+		},
+		"nominal, replace": {
+			policy:  models.ConcurrencyReplace,
+			wantErr: assert.NoError,
+		},
+		"unknown policy, return error": {
+			policy:  "queue",
+			wantErr: assert.Error,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := IsValidConcurrencyPolicy(tt.policy)
+			tt.wantErr(t, err)
+		})
+	}
+}
+
+func TestIsValidMisfirePolicy(t *testing.T) {
+	tests := map[string]struct {
+		policy  models.MisfirePolicy
+		limit   int
+		wantErr assert.ErrorAssertionFunc
+	}{
+		"nominal, no policy": {
+			policy:  "",
+			wantErr: assert.NoError,
+		},
+		"nominal, run once": {
+			policy:  models.MisfireRunOnce,
+			wantErr: assert.NoError,
+		},
+		"nominal, run all with limit": {
+			policy:  models.MisfireRunAll,
+			limit:   3,
+			wantErr: assert.NoError,
+		},
+		"unknown policy, return error": {
+			policy:  "run_twice",
+			wantErr: assert.Error,
+		},
+		"negative limit, return error": {
+			policy:  models.MisfireRunAll,
+			limit:   -1,
+			wantErr: assert.Error,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := IsValidMisfirePolicy(tt.policy, tt.limit)
+			tt.wantErr(t, err)
+		})
+	}
+}
+
+func TestIsValidParams(t *testing.T) {
+	schema := models.ActionSchema{
+		Params: []models.ParamSchema{
+			{Name: "name", Type: models.ParamString, Required: true},
+			{Name: "count", Type: models.ParamInteger},
+			{Name: "force", Type: models.ParamBoolean},
+			{Name: "args", Type: models.ParamStringList},
+			{Name: "headers", Type: models.ParamStringMap},
+		},
+	}
+
+	tests := map[string]struct {
+		params  models.Params
+		wantErr assert.ErrorAssertionFunc
+	}{
+		"nominal, required only": {
+			params:  models.Params{"name": "backup"},
+			wantErr: assert.NoError,
+		},
+		"nominal, decoded from JSON": {
+			params: models.Params{
+				"name":    "backup",
+				"count":   float64(2),
+				"force":   true,
+				"args":    []any{"-v", "--full"},
+				"headers": map[string]any{"Accept": "application/json"},
+			},
+			wantErr: assert.NoError,
+		},
+		"missing required param, return error": {
+			params:  models.Params{"count": 2},
+			wantErr: assert.Error,
+		},
+		"unknown param, return error": {
+			params:  models.Params{"name": "backup", "verbose": true},
+			wantErr: assert.Error,
+		},
+		"decimal integer, return error": {
+			params:  models.Params{"name": "backup", "count": 2.5},
+			wantErr: assert.Error,
+		},
+		"list of numbers, return error": {
+			params:  models.Params{"name": "backup", "args": []any{float64(1)}},
+			wantErr: assert.Error,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := IsValidParams(schema, tt.params)
+			tt.wantErr(t, err)
+		})
+	}
+}
+
+func TestIsValidWorkflow(t *testing.T) {
+	tests := map[string]struct {
+		workflow []models.Task
+		previous []models.Task
+		wantErr  assert.ErrorAssertionFunc
+	}{
+		"nominal, no dependencies": {
+			workflow: []models.Task{{Action: "deploy"}, {Action: "http"}},
+			wantErr:  assert.NoError,
+		},
+		"nominal, dag": {
+			workflow: []models.Task{
+				{ID: "build", Action: "exec"},
+				{ID: "test", Action: "exec", DependsOn: []string{"build"}},
+				{ID: "deploy", Action: "deploy", DependsOn: []string{"build", "test"}},
+			},
+			wantErr: assert.NoError,
+		},
+		"duplicate id, return error": {
+			workflow: []models.Task{{ID: "build", Action: "exec"}, {ID: "build", Action: "exec"}},
+			wantErr:  assert.Error,
+		},
+		"unknown reference, return error": {
+			workflow: []models.Task{{ID: "deploy", Action: "deploy", DependsOn: []string{"build"}}},
+			wantErr:  assert.Error,
+		},
+		"self dependency, return error": {
+			workflow: []models.Task{{ID: "deploy", Action: "deploy", DependsOn: []string{"deploy"}}},
+			wantErr:  assert.Error,
+		},
+		"nominal, templates referencing upstream tasks": {
+			workflow: []models.Task{
+				{ID: "build", Action: "exec"},
+				{ID: "deploy", Action: "deploy", DependsOn: []string{"build"}, Params: models.Params{"artifact": "{{ tasks.build.outputs.stdout }}"}},
+				{ID: "notify", Action: "http", DependsOn: []string{"deploy"}, Params: models.Params{"body": "{{ tasks.build.outputs.stdout }} at {{ run.scheduled_time }}"}},
+			},
+			wantErr: assert.NoError,
+		},
+		"template referencing a parallel task, return error": {
+			workflow: []models.Task{
+				{ID: "build", Action: "exec"},
+				{ID: "docs", Action: "exec"},
+				{ID: "deploy", Action: "deploy", DependsOn: []string{"build"}, Params: models.Params{"artifact": "{{ tasks.docs.outputs.stdout }}"}},
+			},
+			wantErr: assert.Error,
+		},
+		"template referencing an unknown task, return error": {
+			workflow: []models.Task{{ID: "deploy", Action: "deploy", Params: models.Params{"artifact": "{{ tasks.build.outputs.stdout }}"}}},
+			wantErr:  assert.Error,
+		},
+		"invalid id, return error": {
+			workflow: []models.Task{{ID: "build.linux", Action: "exec"}},
+			wantErr:  assert.Error,
+		},
+		"nominal, on failure templates referencing main tasks": {
+			workflow: []models.Task{{ID: "rollback", Action: "deploy", Params: models.Params{"artifact": "{{ tasks.current.outputs.stdout }}"}}},
+			previous: []models.Task{{ID: "current", Action: "exec"}, {ID: "deploy", Action: "deploy"}},
+			wantErr:  assert.NoError,
+		},
+		"on failure id used by main task, return error": {
+			workflow: []models.Task{{ID: "deploy", Action: "deploy"}},
+			previous: []models.Task{{ID: "deploy", Action: "deploy"}},
+			wantErr:  assert.Error,
+		},
+		"nominal, failure condition after a task": {
+			workflow: []models.Task{
+				{ID: "deploy", Action: "deploy"},
+				{ID: "rollback", Action: "deploy", When: &models.Condition{Status: models.ConditionFailure}},
+			},
+			wantErr: assert.NoError,
+		},
+		"failure condition without upstream task, return error": {
+			workflow: []models.Task{
+				{ID: "rollback", Action: "deploy", When: &models.Condition{Status: models.ConditionFailure}},
+				{ID: "deploy", Action: "deploy"},
+			},
+			wantErr: assert.Error,
+		},
+		"failure condition on a root of a dag, return error": {
+			workflow: []models.Task{
+				{ID: "deploy", Action: "deploy"},
+				{ID: "notify", Action: "http", DependsOn: []string{"deploy"}},
+				{ID: "rollback", Action: "deploy", When: &models.Condition{Status: models.ConditionFailure}},
+			},
+			wantErr: assert.Error,
+		},
+		"cycle, return error": {
+			workflow: []models.Task{
+				{ID: "a", Action: "exec", DependsOn: []string{"c"}},
+				{ID: "b", Action: "exec", DependsOn: []string{"a"}},
+				{ID: "c", Action: "exec", DependsOn: []string{"b"}},
+			},
+			wantErr: assert.Error,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := IsValidWorkflow(tt.workflow, tt.previous)
+			tt.wantErr(t, err)
+		})
+	}
+}
+
+func TestIsValidWorkflowErrorNamesTask(t *testing.T) {
+	tests := map[string]struct {
+		workflow []models.Task
+		wantErr  string
+	}{
+		"task with id": {
+			workflow: []models.Task{{ID: "deploy", Action: "deploy", DependsOn: []string{"build"}}},
+			wantErr:  "task deploy depends on unknown task build",
+		},
+		"task without id": {
+			workflow: []models.Task{{ID: "build", Action: "exec"}, {Action: "deploy", Params: models.Params{"artifact": "{{ tasks.docs.outputs.stdout }}"}}},
+			wantErr:  "invalid template in task at position 1: unknown task docs",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := IsValidWorkflow(tt.workflow, nil)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestIsValidCondition(t *testing.T) {
+	tests := map[string]struct {
+		condition *models.Condition
+		wantErr   assert.ErrorAssertionFunc
+	}{
+		"no condition": {
+			condition: nil,
+			wantErr:   assert.NoError,
+		},
+		"nominal": {
+			condition: &models.Condition{Weekdays: []string{"mon", "FRI"}, Status: models.ConditionFailure},
+			wantErr:   assert.NoError,
+		},
+		"unknown weekday, return error": {
+			condition: &models.Condition{Weekdays: []string{"monday"}},
+			wantErr:   assert.Error,
+		},
+		"unknown status, return error": {
+			condition: &models.Condition{Status: "sometimes"},
+			wantErr:   assert.Error,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := IsValidCondition(tt.condition)
+			tt.wantErr(t, err)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package helpers
+
+import "github.com/tobg/scheduler/models"
+
+// Dependencies returns the positions of the tasks each task of a workflow depends on,
+// in a workflow without any dependency each task depends on the previous one.
+// References to unknown tasks are ignored, they are rejected on registration.
+func Dependencies(workflow []models.Task) [][]int {
+	positions := make(map[string]int, len(workflow))
+	explicit := false
+	for i, t := range workflow {
+		if t.ID != "" {
+			positions[t.ID] = i
+		}
+		if len(t.DependsOn) > 0 {
+			explicit = true
+		}
+	}
+
+	deps := make([][]int, len(workflow))
+	for i, t := range workflow {
+		if !explicit {
+			if i > 0 {
+				deps[i] = []int{i - 1}
+			}
+			continue
+		}
+
+		for _, id := range t.DependsOn {
+			if p, exists := positions[id]; exists {
+				deps[i] = append(deps[i], p)
+			}
+		}
+	}
+
+	return deps
+}
+
+// Ancestors returns the positions of the tasks that must succeed before the task
+// at the given position starts, directly or through other tasks
+func Ancestors(deps [][]int, position int) map[int]bool {
+	ancestors := make(map[int]bool)
+
+	stack := append([]int(nil), deps[position]...)
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if ancestors[p] {
+			continue
+		}
+		ancestors[p] = true
+		stack = append(stack, deps[p]...)
+	}
+
+	return ancestors
+}
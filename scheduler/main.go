@@ -2,17 +2,24 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"io/fs"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // job timezones do not depend on the zoneinfo of the host
 
 	"github.com/joho/godotenv"
+	"github.com/tobg/scheduler/actions"
+	"github.com/tobg/scheduler/config"
 	"github.com/tobg/scheduler/controllers"
 	"github.com/tobg/scheduler/database"
+	"github.com/tobg/scheduler/helpers/validations"
 	"github.com/tobg/scheduler/repositories"
 	"github.com/tobg/scheduler/usecases"
 )
@@ -21,34 +28,261 @@ import (
 type App struct {
 	Port               string
 	RegisterController *controllers.RegisterController
+	Scheduler          *usecases.Scheduler
 }
 
 func main() {
-	app, err := initApp()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err := migrate(os.Args[2:])
+		if err != nil {
+			fatal("could not migrate database: %v", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "check" {
+		err := check(os.Args[2:])
+		if err != nil {
+			fatal("could not check database: %v", err)
+		}
+		return
+	}
+
+	flags := config.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	cfg, err := loadConfig(flags)
 	if err != nil {
-		log.Fatal("could not init application: %w", err)
+		fatal("could not load configuration: %v", err)
+	}
+
+	app, err := initApp(cfg)
+	if err != nil {
+		fatal("could not init application: %v", err)
 	}
 
 	app.SetupRoutes()
 	err = app.Serve()
 	if err != nil {
-		log.Fatal("could not start application: %w", err)
+		fatal("could not start application: %v", err)
 	}
 }
 
-// initialization of app (port, controllers etc...)
-func initApp() (*App, error) {
-	if err := godotenv.Load(); err != nil {
-		return nil, fmt.Errorf("error loading .env file: %w", err)
+// fatal logs an error whatever the log level and exits
+func fatal(format string, args ...any) {
+	slog.Error(fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
+
+// loadConfig reads the .env file when there is one, then the configuration
+// and sets the default logger to its log level
+func loadConfig(flags *config.Flags) (config.Config, error) {
+	err := godotenv.Load()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return config.Config{}, fmt.Errorf("error loading .env file: %w", err)
 	}
 
-	db, err := database.InitializeDB()
+	cfg, err := config.Load(flags)
+	if err != nil {
+		return config.Config{}, err
+	}
+
+	slog.SetDefault(cfg.Logger(os.Stderr))
+
+	return cfg, nil
+}
+
+// dbOptions returns the database options of the configuration
+func dbOptions(cfg config.Config) database.Options {
+	return database.Options{
+		Driver:      database.Driver(cfg.DB.Driver),
+		Path:        cfg.DB.Path,
+		URL:         cfg.DB.URL,
+		JournalMode: cfg.DB.JournalMode,
+		BusyTimeout: cfg.DB.BusyTimeout,
+	}
+}
+
+// migrate runs the migrate subcommand: "migrate" applies the pending migrations,
+// "migrate status" lists the migrations and "migrate --dry-run" the ones it would apply
+func migrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "list the pending migrations without applying them")
+	flags := config.BindFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: scheduler migrate [status] [--dry-run]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	// the flags may follow the command
+	command := fs.Arg(0)
+	if command != "" {
+		fs.Parse(fs.Args()[1:])
+	}
+
+	cfg, err := loadConfig(flags)
+	if err != nil {
+		return err
+	}
+
+	opts := dbOptions(cfg)
+	db, err := database.Open(opts)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch command {
+	case "status":
+		status, err := database.Status(db, opts.Driver)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range status {
+			applied := "pending"
+			if s.Applied() {
+				applied = "applied " + s.AppliedAt.Local().Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%-24s %s\n", s.Version, s.Name, applied)
+		}
+		return nil
+	case "":
+		migrations, err := database.Migrate(db, opts.Driver, *dryRun)
+		if err != nil {
+			return err
+		}
+
+		if len(migrations) == 0 {
+			fmt.Println("database is up to date")
+		}
+		for _, m := range migrations {
+			if *dryRun {
+				fmt.Printf("would apply %04d_%v\n", m.Version, m.Name)
+			} else {
+				fmt.Printf("applied %04d_%v\n", m.Version, m.Name)
+			}
+		}
+		return nil
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown migrate command: %v", command)
+	}
+}
+
+// check runs the check subcommand: "check" lists the rows referencing a missing
+// row, such as the tasks of a deleted job, and the jobs with invalid tasks, such as
+// legacy deploy tasks without artifact, "check --clean" deletes the orphan rows
+func check(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	clean := fs.Bool("clean", false, "delete the orphan rows")
+	flags := config.BindFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: scheduler check [--clean]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected argument: %v", fs.Arg(0))
+	}
+
+	cfg, err := loadConfig(flags)
+	if err != nil {
+		return err
+	}
+
+	opts := dbOptions(cfg)
+	db, err := database.Open(opts)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var orphans []database.Orphan
+	if *clean {
+		orphans, err = database.CleanOrphans(db, opts.Driver)
+	} else {
+		orphans, err = database.CheckOrphans(db, opts.Driver)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(orphans) == 0 {
+		fmt.Println("no orphan row")
+	}
+	for _, o := range orphans {
+		if *clean {
+			fmt.Printf("deleted %v %d, missing %v\n", o.Table, o.RowID, o.Parent)
+		} else {
+			fmt.Printf("orphan %v %d, missing %v\n", o.Table, o.RowID, o.Parent)
+		}
+	}
+
+	// the tasks are checked as on registration, the exec task against the configured binaries
+	configureActions(cfg)
+	rr := repositories.RegisterInterface(repositories.NewRegisterRepository(db))
+	if opts.Driver == database.Postgres {
+		rr = repositories.NewPostgresRepository(db)
+	}
+
+	invalid, err := usecases.CheckJobs(rr)
+	if err != nil {
+		return err
+	}
+
+	if len(invalid) == 0 {
+		fmt.Println("no invalid job")
+	}
+	for _, j := range invalid {
+		fmt.Printf("invalid job %d '%v', update or delete it: %v\n", j.ID, j.Label, j.Err)
+	}
+	return nil
+}
+
+// configureActions applies the configuration of the actions
+func configureActions(cfg config.Config) {
+	validations.ConfigureExec(actions.ExecOptions{
+		AllowedBinaries: cfg.Exec.AllowedBinaries,
+		EnvAllowlist:    cfg.Exec.EnvAllowlist,
+	})
+}
+
+// newRepository returns the repository of the configured storage,
+// the database is initialized unless the service is ephemeral
+func newRepository(cfg config.Config) (repositories.RegisterInterface, error) {
+	if cfg.Ephemeral {
+		slog.Warn("ephemeral mode, the jobs are kept in memory and lost on exit")
+		return repositories.NewMemoryRepository(), nil
+	}
+
+	opts := dbOptions(cfg)
+	db, err := database.InitializeDB(opts)
 	if err != nil {
 		return nil, fmt.Errorf("could not initialize database: %w", err)
 	}
 
-	rr := repositories.NewRegisterRepository(db)
-	ru := usecases.NewRegisterUsecase(rr)
+	if opts.Driver == database.Postgres {
+		return repositories.NewPostgresRepository(db), nil
+	}
+	return repositories.NewRegisterRepository(db), nil
+}
+
+// initialization of app (port, controllers etc...)
+func initApp(cfg config.Config) (*App, error) {
+	rr, err := newRepository(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	configureActions(cfg)
+	ex := usecases.NewExecutor(validations.Tasks, cfg.Executor.MaxParallelTasks)
+	jh := usecases.NewJobHandler(rr, ex)
+	sc := usecases.NewScheduler(jh)
+	ru := usecases.NewRegisterUsecase(rr, sc)
 	rc := controllers.NewRegisterController(ru)
 
 	err = rc.ReloadJobs()
@@ -57,8 +291,9 @@ func initApp() (*App, error) {
 	}
 
 	return &App{
-		Port:               os.Getenv("PORT"),
+		Port:               cfg.Listen,
 		RegisterController: rc,
+		Scheduler:          sc,
 	}, nil
 }
 
@@ -66,6 +301,14 @@ func initApp() (*App, error) {
 func (app *App) SetupRoutes() {
 	http.Handle("/register", http.HandlerFunc(app.RegisterController.Register))
 	http.Handle("/get-jobs", http.HandlerFunc(app.RegisterController.GetJobs))
+	http.Handle("/jobs/{id}", http.HandlerFunc(app.RegisterController.Job))
+	http.Handle("/jobs/{id}/runs", http.HandlerFunc(app.RegisterController.GetJobRuns))
+	http.Handle("/jobs/{id}/pause", http.HandlerFunc(app.RegisterController.PauseJob))
+	http.Handle("/jobs/{id}/resume", http.HandlerFunc(app.RegisterController.ResumeJob))
+	http.Handle("/jobs/{id}/trigger", http.HandlerFunc(app.RegisterController.TriggerJob))
+	http.Handle("/jobs/{id}/next", http.HandlerFunc(app.RegisterController.GetNextRuns))
+	http.Handle("/schedule/preview", http.HandlerFunc(app.RegisterController.PreviewSchedule))
+	http.Handle("/actions", http.HandlerFunc(app.RegisterController.GetActions))
 }
 
 // Graceful shutdown setup
@@ -82,7 +325,7 @@ func (app *App) Serve() error {
 	// Run the server in a goroutine
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server error: %s", err)
+			fatal("Server error: %s", err)
 		}
 	}()
 	fmt.Printf("Listening on %s\n", app.Port)
@@ -94,5 +337,9 @@ func (app *App) Serve() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	return srv.Shutdown(ctx)
+	err := srv.Shutdown(ctx)
+
+	// Stop firing jobs and wait for the running ones, even when the server did not
+	// shut down in time, the running executions are then cancelled right away
+	return errors.Join(err, app.Scheduler.Stop(ctx))
 }
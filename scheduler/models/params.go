@@ -0,0 +1,52 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Params are the named parameters of a task
+type Params map[string]any
+
+// Decode fills v, usually a struct with json tags, with the params
+func (p Params) Decode(v any) error {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("could not encode params: %w", err)
+	}
+
+	err = json.Unmarshal(b, v)
+	if err != nil {
+		return fmt.Errorf("could not decode params: %w", err)
+	}
+
+	return nil
+}
+
+// ParamType is the type of the value of a task parameter
+type ParamType string
+
+const (
+	ParamString     ParamType = "string"
+	ParamInteger    ParamType = "integer"
+	ParamBoolean    ParamType = "boolean"
+	ParamStringList ParamType = "string_list" // JSON array of strings
+	ParamStringMap  ParamType = "string_map"  // JSON object of strings
+)
+
+// ParamSchema describes a single parameter accepted by an action
+type ParamSchema struct {
+	Name        string    `json:"name"`
+	Type        ParamType `json:"type"`
+	Required    bool      `json:"required"`
+	Description string    `json:"description"`
+	// NoTemplates is set when the value must be known at registration
+	NoTemplates bool `json:"no_templates,omitempty"`
+}
+
+// ActionSchema describes the parameters accepted by an action
+type ActionSchema struct {
+	Action      string        `json:"action"`
+	Description string        `json:"description"`
+	Params      []ParamSchema `json:"params"`
+}
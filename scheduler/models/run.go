@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// RunStatus represents the outcome of a job or a task execution
+type RunStatus string
+
+const (
+	RunStatusRunning   RunStatus = "running"
+	RunStatusSuccess   RunStatus = "success"
+	RunStatusFailed    RunStatus = "failed"
+	RunStatusSkipped   RunStatus = "skipped"
+	RunStatusTimedOut  RunStatus = "timed_out"
+	RunStatusCancelled RunStatus = "cancelled"
+)
+
+// RunTrigger tells what started a run
+type RunTrigger string
+
+const (
+	RunTriggerScheduled RunTrigger = "scheduled"
+	RunTriggerManual    RunTrigger = "manual"
+	RunTriggerCatchUp   RunTrigger = "catch_up" // an occurrence missed while the service was down
+)
+
+// RunOptions tunes a single execution of a job
+type RunOptions struct {
+	Trigger           RunTrigger
+	ConsumeOccurrence bool
+	ScheduledAt       time.Time
+
+	// OnStart, when set, is called with the run once saved, before its workflow executes
+	OnStart func(JobRun)
+}
+
+// TriggerRequest represents a request to run a job immediately,
+// Params overrides the params of the tasks at the given workflow positions
+type TriggerRequest struct {
+	Params            map[int]Params `json:"params,omitempty"`
+	ConsumeOccurrence bool           `json:"consume_occurrence"`
+}
+
+// JobRun represents a single execution of a job workflow
+type JobRun struct {
+	ID          int        `json:"id"`
+	JobID       int        `json:"job_id"`
+	Attempt     int        `json:"attempt"`
+	Trigger     RunTrigger `json:"trigger"`
+	Status      RunStatus  `json:"status"`
+	ScheduledAt time.Time  `json:"scheduled_at"`
+	StartedAt   time.Time  `json:"started_at"`
+	EndedAt     time.Time  `json:"ended_at"`
+	Error       string     `json:"error,omitempty"`
+	Tasks       []TaskRun  `json:"tasks"`
+}
+
+// TaskRun represents the execution of a single task of a workflow
+type TaskRun struct {
+	TaskID    string        `json:"task_id,omitempty"`
+	Phase     WorkflowPhase `json:"phase,omitempty"`
+	Action    string        `json:"action"`
+	Attempt   int           `json:"attempt"`
+	Status    RunStatus     `json:"status"`
+	StartedAt time.Time     `json:"started_at"`
+	EndedAt   time.Time     `json:"ended_at"`
+	Error     string        `json:"error,omitempty"`
+	Outputs   TaskOutputs   `json:"outputs,omitempty"`
+}
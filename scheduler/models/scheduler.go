@@ -1,36 +1,145 @@
 package models
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // Type Job represents a job to run composed of multiple tasks
 type Job struct {
-	ID           int       `json:"id"`
-	Schedule     time.Time `json:"schedule,omitempty"`      // "DD-MM-YYY HH:MM"
-	UserSchedule string    `json:"user_schedule,omitempty"` // "DD-MM-YYY HH:MM" in string
-	Occurrences  int       `json:"occurrences"`
-	Label        string    `json:"label"`
-	Frequency    string    `json:"frequency"`
-	Workflow     []Task    `json:"workflow"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID           int               `json:"id"`
+	Schedule     time.Time         `json:"schedule,omitempty"`      // "DD-MM-YYY HH:MM"
+	UserSchedule string            `json:"user_schedule,omitempty"` // "DD-MM-YYY HH:MM" in the job timezone or RFC 3339
+	Timezone     string            `json:"timezone,omitempty"`      // IANA name, the server timezone when empty
+	Occurrences  int               `json:"occurrences"`
+	Label        string            `json:"label"`
+	Frequency    string            `json:"frequency"` // single letter (m, H, D, W, M, Y) or cron expression
+	Workflow     []Task            `json:"workflow"`
+	OnFailure    []Task            `json:"on_failure,omitempty"`
+	Timeout      Duration          `json:"timeout,omitempty"`
+	Concurrency  ConcurrencyPolicy `json:"concurrency_policy"`
+	Misfire      MisfirePolicy     `json:"misfire_policy"`
+	MisfireLimit int               `json:"misfire_limit,omitempty"` // missed occurrences run by run_all
+	Paused       bool              `json:"paused"`
+	NextRun      *time.Time        `json:"next_run,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
 
 	CronTime  string `json:"-"`
 	IsOneTime bool   `json:"-"`
 }
 
-// Task represent a single unit of work in a workflow
+// ConcurrencyPolicy tells what to do when a job fires while a previous execution is still running
+type ConcurrencyPolicy string
+
+const (
+	ConcurrencyAllow   ConcurrencyPolicy = "allow"   // run both executions
+	ConcurrencyForbid  ConcurrencyPolicy = "forbid"  // skip the new execution
+	ConcurrencyReplace ConcurrencyPolicy = "replace" // cancel the running execution then start the new one
+)
+
+// MisfirePolicy tells what to do with the occurrences of a job missed while the service was down
+type MisfirePolicy string
+
+const (
+	MisfireSkip    MisfirePolicy = "skip"     // wait for the next occurrence
+	MisfireRunOnce MisfirePolicy = "run_once" // run the last missed occurrence
+	MisfireRunAll  MisfirePolicy = "run_all"  // run the last missed occurrences, up to the misfire limit
+)
+
+// DefaultMisfireLimit is the number of missed occurrences run by run_all when the job sets no limit
+const DefaultMisfireLimit = 10
+
+// ScheduleEntry represents a job registered in the scheduler
+type ScheduleEntry struct {
+	JobID int       `json:"job_id"`
+	Label string    `json:"label"`
+	Next  time.Time `json:"next"`
+}
+
+// PreviewRequest represents a schedule to preview before registering a job,
+// Start is the first run, in the format of a user schedule, now when empty
+type PreviewRequest struct {
+	Frequency string `json:"frequency"`
+	Start     string `json:"start,omitempty"`
+	Timezone  string `json:"timezone,omitempty"`
+	Count     int    `json:"count,omitempty"`
+}
+
+// SchedulePreview lists the next fire times of a schedule, in its timezone
+type SchedulePreview struct {
+	Frequency string      `json:"frequency"`
+	Timezone  string      `json:"timezone,omitempty"`
+	NextRuns  []time.Time `json:"next_runs"`
+}
+
+// Task represent a single unit of work in a workflow.
+// Args are the legacy positional parameters, they are converted to Params on registration.
+// A task starts once the tasks listed in DependsOn succeeded, when no task of a workflow
+// has dependencies the tasks run one after the other. When changes the conditions to run it.
 type Task struct {
-	JobID  int      `json:"id"`
-	Action string   `json:"action"`
-	Args   []string `json:"args"`
+	// ID identifies the task in its workflow, "id" remains the job id as before task ids
+	ID        string       `json:"task_id,omitempty"`
+	JobID     int          `json:"id"`
+	Action    string       `json:"action"`
+	Params    Params       `json:"params"`
+	Args      []string     `json:"args,omitempty"`
+	DependsOn []string     `json:"depends_on,omitempty"`
+	When      *Condition   `json:"when,omitempty"`
+	Retry     *RetryPolicy `json:"retry,omitempty"`
+	Timeout   Duration     `json:"timeout,omitempty"`
+}
+
+// Condition tells when a task runs, Weekdays (mon, tue...) restricts the days of the
+// scheduled time of the run and Status the outcome its dependencies must have
+type Condition struct {
+	Weekdays []string        `json:"weekdays,omitempty"`
+	Status   ConditionStatus `json:"status,omitempty"`
+}
+
+// ConditionStatus is the outcome of its dependencies a task waits for
+type ConditionStatus string
+
+const (
+	ConditionSuccess ConditionStatus = "success" // every dependency succeeded, the default
+	ConditionFailure ConditionStatus = "failure" // a dependency failed, timed out or was cancelled
+	ConditionAlways  ConditionStatus = "always"  // every dependency ended, whatever its outcome
+)
+
+// WorkflowPhase tells which workflow of a job a task belongs to
+type WorkflowPhase string
+
+const (
+	PhaseMain      WorkflowPhase = "main"
+	PhaseOnFailure WorkflowPhase = "on_failure" // runs when the main workflow fails
+)
+
+// RetryPolicy tells how a failing task is retried, the delay between two attempts
+// starts at InitialDelay and is multiplied by Multiplier after each attempt up to MaxDelay.
+// When RetryOn is set only the errors matching one of its regular expressions are retried.
+type RetryPolicy struct {
+	MaxAttempts  int      `json:"max_attempts"`
+	InitialDelay Duration `json:"initial_delay"`
+	Multiplier   float64  `json:"multiplier,omitempty"`
+	MaxDelay     Duration `json:"max_delay,omitempty"`
+	RetryOn      []string `json:"retry_on,omitempty"`
 }
 
 // TaskHandler is used to create the execute function and verify function
-// of each task
+// of each task, the schema describes the params of the task and ParseArgs
+// converts the legacy positional args to params
 type TaskHandler struct {
-	Execute ActionFunc
-	Verify  VerifyFunc
+	Execute   ActionFunc
+	Verify    VerifyFunc
+	ParseArgs ParseArgsFunc
+	Schema    ActionSchema
 }
 
-// types of functions used in TaskHandler
-type ActionFunc func([]string) error
-type VerifyFunc func([]string) error
+// types of functions used in TaskHandler.
+// An ActionFunc must return promptly once its context is done: a timed out or cancelled
+// task is abandoned by the executor, which cannot stop a function ignoring its context.
+type ActionFunc func(context.Context, Params) (TaskOutputs, error)
+type VerifyFunc func(Params) error
+type ParseArgsFunc func([]string) (Params, error)
+
+// TaskOutputs are the named values produced by a task execution
+type TaskOutputs map[string]string
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskJSON(t *testing.T) {
+	tests := map[string]struct {
+		payload  string
+		wantTask Task
+	}{
+		"nominal, task without task id": {
+			payload:  `{"id": 7, "action": "deploy", "args": ["civic-assistant", "/home/apps/civic-assistant", "/tmp/civic-assistant.tar.gz"]}`,
+			wantTask: Task{JobID: 7, Action: "deploy", Args: []string{"civic-assistant", "/home/apps/civic-assistant", "/tmp/civic-assistant.tar.gz"}},
+		},
+		"nominal, task id": {
+			payload:  `{"id": 7, "task_id": "deploy", "action": "deploy", "depends_on": ["build"]}`,
+			wantTask: Task{ID: "deploy", JobID: 7, Action: "deploy", DependsOn: []string{"build"}},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var task Task
+			require.NoError(t, json.Unmarshal([]byte(tt.payload), &task))
+			assert.Equal(t, tt.wantTask, task)
+
+			b, err := json.Marshal(task)
+			require.NoError(t, err)
+
+			var fields map[string]any
+			require.NoError(t, json.Unmarshal(b, &fields))
+			assert.Equal(t, float64(7), fields["id"], "id remains the job id")
+		})
+	}
+}
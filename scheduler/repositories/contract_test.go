@@ -0,0 +1,336 @@
+package repositories
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tobg/scheduler/models"
+)
+
+// testJob returns a job using every field the repositories store
+func testJob(label string) models.Job {
+	return models.Job{
+		Schedule:     time.Date(2030, time.March, 15, 9, 30, 0, 0, time.UTC),
+		UserSchedule: "15-03-2030 10:30",
+		Timezone:     "Europe/Paris",
+		Occurrences:  3,
+		Label:        label,
+		Frequency:    "D",
+		CronTime:     "0 30 9 * * *",
+		Timeout:      models.Duration(time.Minute),
+		Concurrency:  models.ConcurrencyForbid,
+		Misfire:      models.MisfireRunAll,
+		MisfireLimit: 5,
+		Workflow: []models.Task{
+			{
+				ID:      "backup",
+				Action:  "exec",
+				Params:  models.Params{"binary": "/bin/true", "dir": "/tmp"},
+				Retry:   &models.RetryPolicy{MaxAttempts: 3, InitialDelay: models.Duration(time.Second)},
+				Timeout: models.Duration(30 * time.Second),
+			},
+			{
+				ID:        "notify",
+				Action:    "exec",
+				Params:    models.Params{"binary": "/bin/true", "dir": "/tmp"},
+				DependsOn: []string{"backup"},
+				When:      &models.Condition{Weekdays: []string{"mon"}, Status: models.ConditionAlways},
+			},
+		},
+		OnFailure: []models.Task{
+			{ID: "alert", Action: "exec", Params: models.Params{"binary": "/bin/true", "dir": "/tmp"}},
+		},
+	}
+}
+
+// assertJob checks a retrieved job matches the registered one
+func assertJob(t *testing.T, want models.Job, got models.Job) {
+	t.Helper()
+
+	assert.True(t, want.Schedule.Equal(got.Schedule), "schedule: got %v, want %v", got.Schedule, want.Schedule)
+	assert.Equal(t, want.UserSchedule, got.UserSchedule)
+	assert.Equal(t, want.Timezone, got.Timezone)
+	assert.Equal(t, want.Occurrences, got.Occurrences)
+	assert.Equal(t, want.Label, got.Label)
+	assert.Equal(t, want.Frequency, got.Frequency)
+	assert.Equal(t, want.Timeout, got.Timeout)
+	assert.Equal(t, want.Concurrency, got.Concurrency)
+	assert.Equal(t, want.Misfire, got.Misfire)
+	assert.Equal(t, want.MisfireLimit, got.MisfireLimit)
+	assert.False(t, got.CreatedAt.IsZero(), "created_at is set")
+
+	for i := range want.Workflow {
+		want.Workflow[i].JobID = got.ID
+	}
+	for i := range want.OnFailure {
+		want.OnFailure[i].JobID = got.ID
+	}
+	assert.Equal(t, want.Workflow, got.Workflow)
+	assert.Equal(t, want.OnFailure, got.OnFailure)
+}
+
+// testRepositoryContract runs the behaviour every RegisterInterface implementation
+// must have, newRepository returns an empty repository
+func testRepositoryContract(t *testing.T, newRepository func(t *testing.T) RegisterInterface) {
+	t.Run("register and retrieve a job", func(t *testing.T) {
+		rr := newRepository(t)
+		want := testJob("backup")
+
+		id, err := rr.RegisterJob(want)
+		require.NoError(t, err)
+		assert.NotZero(t, id)
+
+		got, err := rr.RetrieveJob(id)
+		require.NoError(t, err)
+		assert.Equal(t, id, got.ID)
+		assertJob(t, want, got)
+	})
+
+	t.Run("retrieved jobs are copies", func(t *testing.T) {
+		rr := newRepository(t)
+		id, err := rr.RegisterJob(testJob("backup"))
+		require.NoError(t, err)
+
+		got, err := rr.RetrieveJob(id)
+		require.NoError(t, err)
+		got.Workflow[0].Params["binary"] = "/bin/false"
+		got.Workflow[0].Retry.MaxAttempts = 1
+
+		got, err = rr.RetrieveJob(id)
+		require.NoError(t, err)
+		assertJob(t, testJob("backup"), got)
+	})
+
+	t.Run("retrieve a missing job, return ErrJobNotFound", func(t *testing.T) {
+		rr := newRepository(t)
+
+		_, err := rr.RetrieveJob(42)
+		assert.True(t, errors.Is(err, ErrJobNotFound), "got %v", err)
+	})
+
+	t.Run("retrieve jobs", func(t *testing.T) {
+		rr := newRepository(t)
+
+		jobs, err := rr.RetrieveJobs()
+		require.NoError(t, err)
+		assert.Empty(t, jobs)
+
+		ids := make(map[int]string)
+		for _, label := range []string{"backup", "report"} {
+			id, err := rr.RegisterJob(testJob(label))
+			require.NoError(t, err)
+			ids[id] = label
+		}
+
+		jobs, err = rr.RetrieveJobs()
+		require.NoError(t, err)
+		require.Len(t, jobs, 2)
+		for _, j := range jobs {
+			assertJob(t, testJob(ids[j.ID]), j)
+		}
+	})
+
+	t.Run("update a job", func(t *testing.T) {
+		rr := newRepository(t)
+		id, err := rr.RegisterJob(testJob("backup"))
+		require.NoError(t, err)
+
+		want := testJob("nightly backup")
+		want.ID = id
+		want.Occurrences = -1
+		want.Timezone = ""
+		want.Workflow = want.Workflow[:1]
+		want.OnFailure = nil
+		require.NoError(t, rr.UpdateJob(want))
+
+		got, err := rr.RetrieveJob(id)
+		require.NoError(t, err)
+		assertJob(t, want, got)
+
+		want.ID = id + 1
+		err = rr.UpdateJob(want)
+		assert.True(t, errors.Is(err, ErrJobNotFound), "got %v", err)
+	})
+
+	t.Run("delete a job", func(t *testing.T) {
+		rr := newRepository(t)
+		id, err := rr.RegisterJob(testJob("backup"))
+		require.NoError(t, err)
+
+		_, err = rr.CreateRun(models.JobRun{JobID: id, Trigger: models.RunTriggerManual, Status: models.RunStatusRunning, StartedAt: time.Now()})
+		require.NoError(t, err)
+
+		require.NoError(t, rr.DeleteJob(id))
+
+		_, err = rr.RetrieveJob(id)
+		assert.True(t, errors.Is(err, ErrJobNotFound), "got %v", err)
+
+		jobs, err := rr.RetrieveJobs()
+		require.NoError(t, err)
+		assert.Empty(t, jobs)
+
+		// the history of a deleted job is kept
+		runs, err := rr.RetrieveRuns(id, 10)
+		require.NoError(t, err)
+		assert.Len(t, runs, 1)
+
+		err = rr.DeleteJob(id)
+		assert.True(t, errors.Is(err, ErrJobNotFound), "got %v", err)
+	})
+
+	t.Run("decrement occurrences", func(t *testing.T) {
+		rr := newRepository(t)
+		id, err := rr.RegisterJob(testJob("backup"))
+		require.NoError(t, err)
+
+		left, err := rr.DecrementJobOccurrences(id)
+		require.NoError(t, err)
+		assert.Equal(t, 2, left)
+
+		_, err = rr.DecrementJobOccurrences(id + 1)
+		assert.True(t, errors.Is(err, ErrJobNotFound), "got %v", err)
+	})
+
+	t.Run("pause and resume a job", func(t *testing.T) {
+		rr := newRepository(t)
+		id, err := rr.RegisterJob(testJob("backup"))
+		require.NoError(t, err)
+
+		require.NoError(t, rr.SetJobPaused(id, true))
+		got, err := rr.RetrieveJob(id)
+		require.NoError(t, err)
+		assert.True(t, got.Paused)
+
+		require.NoError(t, rr.SetJobPaused(id, false))
+		got, err = rr.RetrieveJob(id)
+		require.NoError(t, err)
+		assert.False(t, got.Paused)
+
+		err = rr.SetJobPaused(id+1, true)
+		assert.True(t, errors.Is(err, ErrJobNotFound), "got %v", err)
+	})
+
+	t.Run("concurrent runs get distinct attempts", func(t *testing.T) {
+		rr := newRepository(t)
+		id, err := rr.RegisterJob(testJob("backup"))
+		require.NoError(t, err)
+
+		const count = 8
+		attempts := make(chan int, count)
+		var wg sync.WaitGroup
+		for i := 0; i < count; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				run, err := rr.CreateRun(models.JobRun{JobID: id, Trigger: models.RunTriggerManual, Status: models.RunStatusRunning, StartedAt: time.Now()})
+				assert.NoError(t, err)
+				attempts <- run.Attempt
+			}()
+		}
+		wg.Wait()
+		close(attempts)
+
+		var got []int
+		for a := range attempts {
+			got = append(got, a)
+		}
+		assert.ElementsMatch(t, []int{1, 2, 3, 4, 5, 6, 7, 8}, got)
+	})
+
+	t.Run("save and retrieve runs", func(t *testing.T) {
+		rr := newRepository(t)
+		id, err := rr.RegisterJob(testJob("backup"))
+		require.NoError(t, err)
+
+		last, err := rr.RetrieveLastRun(id)
+		require.NoError(t, err)
+		assert.True(t, last.IsZero(), "the job never ran")
+
+		scheduledAt := time.Date(2030, time.March, 15, 9, 30, 0, 0, time.UTC)
+		startedAt := scheduledAt.Add(time.Second)
+
+		first, err := rr.CreateRun(models.JobRun{JobID: id, Trigger: models.RunTriggerScheduled, Status: models.RunStatusRunning, ScheduledAt: scheduledAt, StartedAt: startedAt})
+		require.NoError(t, err)
+		assert.NotZero(t, first.ID)
+		assert.Equal(t, 1, first.Attempt)
+
+		first.Status = models.RunStatusFailed
+		first.Error = "backup: exit status 1"
+		first.EndedAt = startedAt.Add(time.Minute)
+		first.Tasks = []models.TaskRun{
+			{TaskID: "backup", Action: "exec", Attempt: 1, Status: models.RunStatusFailed, Error: "exit status 1", StartedAt: startedAt, EndedAt: startedAt.Add(time.Minute)},
+			{TaskID: "alert", Phase: models.PhaseOnFailure, Action: "exec", Attempt: 1, Status: models.RunStatusSuccess, Outputs: models.TaskOutputs{"stdout": "sent"}, StartedAt: first.EndedAt, EndedAt: first.EndedAt},
+		}
+		require.NoError(t, rr.CompleteRun(first))
+
+		second, err := rr.CreateRun(models.JobRun{JobID: id, Trigger: models.RunTriggerManual, Status: models.RunStatusRunning, StartedAt: startedAt.Add(time.Hour)})
+		require.NoError(t, err)
+		assert.Equal(t, 2, second.Attempt)
+
+		runs, err := rr.RetrieveRuns(id, 10)
+		require.NoError(t, err)
+		require.Len(t, runs, 2)
+
+		assert.Equal(t, second.ID, runs[0].ID, "most recent run first")
+		assert.Equal(t, models.RunTriggerManual, runs[0].Trigger)
+		assert.Equal(t, models.RunStatusRunning, runs[0].Status)
+		assert.Empty(t, runs[0].Tasks)
+
+		got := runs[1]
+		assert.Equal(t, first.ID, got.ID)
+		assert.Equal(t, id, got.JobID)
+		assert.Equal(t, models.RunStatusFailed, got.Status)
+		assert.Equal(t, first.Error, got.Error)
+		assert.True(t, scheduledAt.Equal(got.ScheduledAt), "scheduled_at: got %v", got.ScheduledAt)
+		assert.True(t, first.EndedAt.Equal(got.EndedAt), "ended_at: got %v", got.EndedAt)
+		require.Len(t, got.Tasks, 2)
+		assert.Equal(t, models.PhaseMain, got.Tasks[0].Phase, "the phase defaults to main")
+		assert.Equal(t, "exit status 1", got.Tasks[0].Error)
+		assert.Equal(t, models.PhaseOnFailure, got.Tasks[1].Phase)
+		assert.Equal(t, models.TaskOutputs{"stdout": "sent"}, got.Tasks[1].Outputs)
+
+		// manual runs are not scheduled occurrences
+		last, err = rr.RetrieveLastRun(id)
+		require.NoError(t, err)
+		assert.True(t, scheduledAt.Equal(last), "last run: got %v, want %v", last, scheduledAt)
+	})
+
+	t.Run("retrieve the most recent runs", func(t *testing.T) {
+		rr := newRepository(t)
+		id, err := rr.RegisterJob(testJob("backup"))
+		require.NoError(t, err)
+
+		// the runs are not created in the order they started, nor in the same time zone
+		start := time.Date(2030, time.March, 15, 9, 30, 0, 0, time.UTC)
+		for _, hours := range []int{2, 0, 4, 1, 3} {
+			startedAt := start.Add(time.Duration(hours) * time.Hour)
+			run, err := rr.CreateRun(models.JobRun{JobID: id, Trigger: models.RunTriggerManual, Status: models.RunStatusRunning, StartedAt: startedAt.In(time.FixedZone("", -2*hours*3600))})
+			require.NoError(t, err)
+
+			run.Status = models.RunStatusSuccess
+			run.EndedAt = startedAt.Add(time.Minute)
+			run.Tasks = []models.TaskRun{
+				{TaskID: "backup", Action: "exec", Attempt: 1, Status: models.RunStatusSuccess, StartedAt: startedAt, EndedAt: startedAt},
+				{TaskID: "notify", Action: "exec", Attempt: 1, Status: models.RunStatusSuccess, StartedAt: startedAt, EndedAt: startedAt},
+			}
+			require.NoError(t, rr.CompleteRun(run))
+		}
+
+		runs, err := rr.RetrieveRuns(id, 3)
+		require.NoError(t, err)
+		require.Len(t, runs, 3, "the limit applies to the runs, not to their tasks")
+		for i, hours := range []int{4, 3, 2} {
+			want := start.Add(time.Duration(hours) * time.Hour)
+			assert.True(t, want.Equal(runs[i].StartedAt), "run %d started at %v, want %v", i, runs[i].StartedAt, want)
+			assert.Len(t, runs[i].Tasks, 2)
+		}
+
+		runs, err = rr.RetrieveRuns(id, 10)
+		require.NoError(t, err)
+		assert.Len(t, runs, 5)
+	})
+}
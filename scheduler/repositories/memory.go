@@ -0,0 +1,297 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tobg/scheduler/models"
+)
+
+// MemoryRepository stores the jobs and their runs in memory, they are lost when the
+// service stops. It behaves as RegisterRepository: deleting a job deletes its workflow
+// and keeps its runs.
+type MemoryRepository struct {
+	mu        sync.Mutex
+	jobs      map[int]models.Job
+	runs      []models.JobRun
+	lastJobID int
+	lastRunID int
+}
+
+// NewMemoryRepository returns an empty in memory repository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		jobs: make(map[int]models.Job),
+	}
+}
+
+func (mr *MemoryRepository) RegisterJob(j models.Job) (int, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	mr.lastJobID++
+	id := mr.lastJobID
+
+	stored, err := storedJob(id, j)
+	if err != nil {
+		return 0, err
+	}
+	stored.Paused = false
+	stored.CreatedAt = time.Now()
+	mr.jobs[id] = stored
+
+	return id, nil
+}
+
+func (mr *MemoryRepository) RetrieveJob(id int) (models.Job, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	j, ok := mr.jobs[id]
+	if !ok {
+		return models.Job{}, fmt.Errorf("%w with id: %d", ErrJobNotFound, id)
+	}
+
+	return storedJob(id, j)
+}
+
+func (mr *MemoryRepository) DeleteJob(id int) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	if _, ok := mr.jobs[id]; !ok {
+		return fmt.Errorf("%w with id: %d", ErrJobNotFound, id)
+	}
+	delete(mr.jobs, id)
+
+	return nil
+}
+
+func (mr *MemoryRepository) DecrementJobOccurrences(id int) (int, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	j, ok := mr.jobs[id]
+	if !ok {
+		return 0, fmt.Errorf("%w with id: %d", ErrJobNotFound, id)
+	}
+	j.Occurrences--
+	mr.jobs[id] = j
+
+	return j.Occurrences, nil
+}
+
+func (mr *MemoryRepository) RetrieveJobs() ([]models.Job, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	var jobs []models.Job
+	for id, j := range mr.jobs {
+		stored, err := storedJob(id, j)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, stored)
+	}
+
+	sort.Slice(jobs, func(i, k int) bool {
+		return jobs[i].ID < jobs[k].ID
+	})
+
+	return jobs, nil
+}
+
+// UpdateJob replaces a job and its workflow, it keeps wether the job is paused
+func (mr *MemoryRepository) UpdateJob(j models.Job) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	current, ok := mr.jobs[j.ID]
+	if !ok {
+		return fmt.Errorf("%w with id: %d", ErrJobNotFound, j.ID)
+	}
+
+	stored, err := storedJob(j.ID, j)
+	if err != nil {
+		return err
+	}
+	stored.Paused = current.Paused
+	stored.CreatedAt = current.CreatedAt
+	mr.jobs[j.ID] = stored
+
+	return nil
+}
+
+// SetJobPaused pauses or resumes a job
+func (mr *MemoryRepository) SetJobPaused(id int, paused bool) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	j, ok := mr.jobs[id]
+	if !ok {
+		return fmt.Errorf("%w with id: %d", ErrJobNotFound, id)
+	}
+	j.Paused = paused
+	mr.jobs[id] = j
+
+	return nil
+}
+
+// CreateRun saves a starting run and returns it with its id and attempt number
+func (mr *MemoryRepository) CreateRun(r models.JobRun) (models.JobRun, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	r.Attempt = 1
+	for _, run := range mr.runs {
+		if run.JobID == r.JobID {
+			r.Attempt++
+		}
+	}
+
+	mr.lastRunID++
+	r.ID = mr.lastRunID
+	r.Error = ""
+	r.EndedAt = time.Time{}
+	r.Tasks = nil
+	mr.runs = append(mr.runs, r)
+
+	return r, nil
+}
+
+// CompleteRun saves the outcome of a run and of each of its tasks
+func (mr *MemoryRepository) CompleteRun(r models.JobRun) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	for i := range mr.runs {
+		if mr.runs[i].ID != r.ID {
+			continue
+		}
+
+		run := &mr.runs[i]
+		run.Status = r.Status
+		run.Error = r.Error
+		run.EndedAt = r.EndedAt
+
+		for _, t := range r.Tasks {
+			if t.Phase == "" {
+				t.Phase = models.PhaseMain
+			}
+			if len(t.Outputs) == 0 {
+				t.Outputs = nil
+			}
+			run.Tasks = append(run.Tasks, t)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("could not update run: run %d not found", r.ID)
+}
+
+// RetrieveRuns returns the limit most recent runs of a job, most recent first
+func (mr *MemoryRepository) RetrieveRuns(jobID, limit int) ([]models.JobRun, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	var runs []models.JobRun
+	for i := len(mr.runs) - 1; i >= 0; i-- {
+		if mr.runs[i].JobID != jobID {
+			continue
+		}
+
+		r := mr.runs[i]
+		r.Tasks = append([]models.TaskRun(nil), r.Tasks...)
+		runs = append(runs, r)
+	}
+
+	// the runs are listed by start time as the databases do, the latest created first on a tie
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].StartedAt.After(runs[j].StartedAt) })
+	if len(runs) > limit {
+		runs = runs[:limit]
+	}
+
+	return runs, nil
+}
+
+// RetrieveLastRun returns the scheduled time of the last scheduled or catch up run of a job,
+// the zero time when the job never ran
+func (mr *MemoryRepository) RetrieveLastRun(jobID int) (time.Time, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	for i := len(mr.runs) - 1; i >= 0; i-- {
+		r := mr.runs[i]
+		if r.JobID != jobID || r.ScheduledAt.IsZero() {
+			continue
+		}
+		if r.Trigger == models.RunTriggerScheduled || r.Trigger == models.RunTriggerCatchUp {
+			return r.ScheduledAt, nil
+		}
+	}
+
+	return time.Time{}, nil
+}
+
+// storedJob returns a copy of a job holding what a database stores, the tasks are
+// copied through JSON as their params, retry policy and condition are references
+func storedJob(id int, j models.Job) (models.Job, error) {
+	stored := models.Job{
+		ID:           id,
+		Schedule:     j.Schedule,
+		UserSchedule: j.UserSchedule,
+		Timezone:     j.Timezone,
+		Occurrences:  j.Occurrences,
+		Label:        j.Label,
+		Frequency:    j.Frequency,
+		Timeout:      j.Timeout,
+		Concurrency:  j.Concurrency,
+		Misfire:      j.Misfire,
+		MisfireLimit: j.MisfireLimit,
+		Paused:       j.Paused,
+		CreatedAt:    j.CreatedAt,
+		IsOneTime:    j.Occurrences == 1,
+	}
+
+	var err error
+	stored.Workflow, err = storedTasks(id, j.Workflow)
+	if err != nil {
+		return models.Job{}, err
+	}
+
+	stored.OnFailure, err = storedTasks(id, j.OnFailure)
+	if err != nil {
+		return models.Job{}, err
+	}
+
+	return stored, nil
+}
+
+// storedTasks returns a copy of the tasks of a workflow, the legacy args are not stored
+func storedTasks(jobID int, tasks []models.Task) ([]models.Task, error) {
+	if len(tasks) == 0 {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(tasks)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode tasks: %w", err)
+	}
+
+	var stored []models.Task
+	err = json.Unmarshal(b, &stored)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode tasks: %w", err)
+	}
+
+	for i := range stored {
+		stored[i].JobID = jobID
+		stored[i].Args = nil
+	}
+
+	return stored, nil
+}
@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"database/sql"
+	_ "embed"
+)
+
+//go:embed queries/postgres/insert_job.sql
+var pgInsertJob string
+
+//go:embed queries/postgres/insert_tasks.sql
+var pgInsertTasks string
+
+//go:embed queries/postgres/get_job_by_id.sql
+var pgGetJob string
+
+//go:embed queries/postgres/delete_job_by_id.sql
+var pgDeleteJob string
+
+//go:embed queries/postgres/update_job_occurences.sql
+var pgDecrementOccurrences string
+
+//go:embed queries/postgres/get_jobs.sql
+var pgGetJobs string
+
+//go:embed queries/postgres/update_job.sql
+var pgUpdateJob string
+
+//go:embed queries/postgres/delete_tasks_by_job_id.sql
+var pgDeleteTasks string
+
+//go:embed queries/postgres/update_job_paused.sql
+var pgUpdateJobPaused string
+
+//go:embed queries/postgres/insert_job_run.sql
+var pgInsertJobRun string
+
+//go:embed queries/postgres/update_job_run.sql
+var pgUpdateJobRun string
+
+//go:embed queries/postgres/insert_task_run.sql
+var pgInsertTaskRun string
+
+//go:embed queries/postgres/get_job_runs.sql
+var pgGetJobRuns string
+
+//go:embed queries/postgres/get_last_run.sql
+var pgGetLastRun string
+
+var postgresQueries = queries{
+	insertJob:            pgInsertJob,
+	insertTasks:          pgInsertTasks,
+	getJob:               pgGetJob,
+	deleteJob:            pgDeleteJob,
+	decrementOccurrences: pgDecrementOccurrences,
+	getJobs:              pgGetJobs,
+	updateJob:            pgUpdateJob,
+	deleteTasks:          pgDeleteTasks,
+	updateJobPaused:      pgUpdateJobPaused,
+	insertJobRun:         pgInsertJobRun,
+	updateJobRun:         pgUpdateJobRun,
+	insertTaskRun:        pgInsertTaskRun,
+	getJobRuns:           pgGetJobRuns,
+	getLastRun:           pgGetLastRun,
+}
+
+// NewPostgresRepository returns a repository storing the jobs in PostgreSQL,
+// the database is opened with the pgx driver
+func NewPostgresRepository(db *sql.DB) *RegisterRepository {
+	return &RegisterRepository{
+		db: db,
+		q:  postgresQueries,
+	}
+}
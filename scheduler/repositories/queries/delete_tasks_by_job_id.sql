@@ -0,0 +1,2 @@
+DELETE FROM workflows
+WHERE job_id = ?;
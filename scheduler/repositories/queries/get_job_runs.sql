@@ -0,0 +1,30 @@
+SELECT
+    r.id,
+    r.job_id,
+    r.attempt,
+    r.trigger,
+    r.status,
+    r.error,
+    r.scheduled_at,
+    r.started_at,
+    r.ended_at,
+
+    t.phase,
+    t.task_id,
+    t.action,
+    t.attempt,
+    t.status,
+    t.error,
+    t.outputs,
+    t.started_at,
+    t.ended_at
+FROM (
+    -- the limit applies to the runs, not to the rows of their tasks
+    SELECT *
+    FROM job_runs
+    WHERE job_id = ?
+    ORDER BY started_at DESC, id DESC
+    LIMIT ?
+) r
+LEFT JOIN task_runs t ON r.id = t.run_id
+ORDER BY r.started_at DESC, r.id DESC, t.position, t.attempt;
@@ -0,0 +1,7 @@
+SELECT scheduled_at
+FROM job_runs
+WHERE job_id = ?
+    AND trigger IN ('scheduled', 'catch_up')
+    AND scheduled_at IS NOT NULL
+ORDER BY id DESC
+LIMIT 1;
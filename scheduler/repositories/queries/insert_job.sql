@@ -1,9 +1,15 @@
 INSERT INTO jobs (
     schedule,
     user_schedule,
+    timezone,
     occurrences,
     frequency,
     label,
-    cron_time
+    cron_time,
+    timeout,
+    concurrency_policy,
+    misfire_policy,
+    misfire_limit
 )
-VALUES (?, ?, ?, ?, ?, ?);
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id;
@@ -0,0 +1,11 @@
+INSERT INTO job_runs (
+    job_id,
+    attempt,
+    trigger,
+    status,
+    scheduled_at,
+    started_at
+)
+VALUES (?, (SELECT COALESCE(MAX(attempt), 0) + 1 FROM job_runs WHERE job_id = ?), ?, ?, ?, ?)
+ON CONFLICT (job_id, attempt) DO NOTHING
+RETURNING id, attempt;
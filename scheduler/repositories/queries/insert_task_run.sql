@@ -0,0 +1,14 @@
+INSERT INTO task_runs (
+    run_id,
+    position,
+    phase,
+    task_id,
+    action,
+    attempt,
+    status,
+    error,
+    outputs,
+    started_at,
+    ended_at
+)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
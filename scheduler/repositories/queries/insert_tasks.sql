@@ -1,3 +1,3 @@
-INSERT INTO workflows (job_id, action, args) 
+INSERT INTO workflows (job_id, task_id, action, params, depends_on, retry, timeout, phase, run_condition) 
 VALUES 
-    (?, ?, ?);
\ No newline at end of file
+    (?, ?, ?, ?, ?, ?, ?, ?, ?);
\ No newline at end of file
@@ -0,0 +1,2 @@
+DELETE FROM jobs
+WHERE id = $1;
\ No newline at end of file
@@ -0,0 +1,27 @@
+SELECT
+    j.id,
+    j.schedule,
+    j.user_schedule,
+    j.timezone,
+    j.occurrences,
+    j.frequency,
+    j.label,
+    j.paused,
+    j.timeout,
+    j.concurrency_policy,
+    j.misfire_policy,
+    j.misfire_limit,
+    j.created_at,
+    
+    w.task_id,
+    w.action,
+    w.args,
+    w.params,
+    w.depends_on,
+    w.retry,
+    w.timeout,
+    w.phase,
+    w.run_condition
+FROM jobs j
+LEFT JOIN workflows w ON j.id = w.job_id
+ORDER BY j.id, w.id;
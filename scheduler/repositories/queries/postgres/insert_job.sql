@@ -0,0 +1,15 @@
+INSERT INTO jobs (
+    schedule,
+    user_schedule,
+    timezone,
+    occurrences,
+    frequency,
+    label,
+    cron_time,
+    timeout,
+    concurrency_policy,
+    misfire_policy,
+    misfire_limit
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING id;
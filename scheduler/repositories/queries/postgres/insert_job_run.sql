@@ -0,0 +1,11 @@
+INSERT INTO job_runs (
+    job_id,
+    attempt,
+    trigger,
+    status,
+    scheduled_at,
+    started_at
+)
+VALUES ($1, (SELECT COALESCE(MAX(attempt), 0) + 1 FROM job_runs WHERE job_id = $2), $3, $4, $5, $6)
+ON CONFLICT (job_id, attempt) DO NOTHING
+RETURNING id, attempt;
@@ -0,0 +1,14 @@
+INSERT INTO task_runs (
+    run_id,
+    position,
+    phase,
+    task_id,
+    action,
+    attempt,
+    status,
+    error,
+    outputs,
+    started_at,
+    ended_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);
@@ -0,0 +1,3 @@
+INSERT INTO workflows (job_id, task_id, action, params, depends_on, retry, timeout, phase, run_condition) 
+VALUES 
+    ($1, $2, $3, $4, $5, $6, $7, $8, $9);
\ No newline at end of file
@@ -0,0 +1,14 @@
+UPDATE jobs
+SET
+    schedule = $1,
+    user_schedule = $2,
+    timezone = $3,
+    occurrences = $4,
+    frequency = $5,
+    label = $6,
+    cron_time = $7,
+    timeout = $8,
+    concurrency_policy = $9,
+    misfire_policy = $10,
+    misfire_limit = $11
+WHERE id = $12;
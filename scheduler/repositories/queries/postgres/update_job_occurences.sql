@@ -0,0 +1,4 @@
+UPDATE jobs
+SET occurrences = occurrences - 1
+WHERE id = $1
+RETURNING occurrences;
\ No newline at end of file
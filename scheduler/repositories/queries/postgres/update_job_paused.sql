@@ -0,0 +1,3 @@
+UPDATE jobs
+SET paused = $1
+WHERE id = $2;
@@ -0,0 +1,3 @@
+UPDATE job_runs
+SET status = $1, error = $2, ended_at = $3
+WHERE id = $4;
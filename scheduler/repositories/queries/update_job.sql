@@ -0,0 +1,14 @@
+UPDATE jobs
+SET
+    schedule = ?,
+    user_schedule = ?,
+    timezone = ?,
+    occurrences = ?,
+    frequency = ?,
+    label = ?,
+    cron_time = ?,
+    timeout = ?,
+    concurrency_policy = ?,
+    misfire_policy = ?,
+    misfire_limit = ?
+WHERE id = ?;
@@ -0,0 +1,3 @@
+UPDATE jobs
+SET paused = ?
+WHERE id = ?;
@@ -0,0 +1,3 @@
+UPDATE job_runs
+SET status = ?, error = ?, ended_at = ?
+WHERE id = ?;
@@ -3,9 +3,11 @@ package repositories
 import (
 	"database/sql"
 	_ "embed"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"strings"
+	"time"
 
 	"github.com/tobg/scheduler/models"
 )
@@ -28,8 +30,57 @@ var decrementOccurrences string
 //go:embed queries/get_jobs.sql
 var getJobs string
 
+//go:embed queries/update_job.sql
+var updateJob string
+
+//go:embed queries/delete_tasks_by_job_id.sql
+var deleteTasks string
+
+//go:embed queries/update_job_paused.sql
+var updateJobPaused string
+
+// ErrJobNotFound is returned when a job does not exist in database
+var ErrJobNotFound = errors.New("job not found")
+
+// queries are the statements of a database engine
+type queries struct {
+	insertJob            string
+	insertTasks          string
+	getJob               string
+	deleteJob            string
+	decrementOccurrences string
+	getJobs              string
+	updateJob            string
+	deleteTasks          string
+	updateJobPaused      string
+	insertJobRun         string
+	updateJobRun         string
+	insertTaskRun        string
+	getJobRuns           string
+	getLastRun           string
+}
+
+var sqliteQueries = queries{
+	insertJob:            insertJob,
+	insertTasks:          insertTasks,
+	getJob:               getJob,
+	deleteJob:            deleteJob,
+	decrementOccurrences: decrementOccurrences,
+	getJobs:              getJobs,
+	updateJob:            updateJob,
+	deleteTasks:          deleteTasks,
+	updateJobPaused:      updateJobPaused,
+	insertJobRun:         insertJobRun,
+	updateJobRun:         updateJobRun,
+	insertTaskRun:        insertTaskRun,
+	getJobRuns:           getJobRuns,
+	getLastRun:           getLastRun,
+}
+
+// RegisterRepository stores the jobs and their runs in a SQL database
 type RegisterRepository struct {
 	db *sql.DB
+	q  queries
 }
 
 type RegisterInterface interface {
@@ -38,11 +89,19 @@ type RegisterInterface interface {
 	DeleteJob(id int) error
 	DecrementJobOccurrences(id int) (int, error)
 	RetrieveJobs() ([]models.Job, error)
+	UpdateJob(j models.Job) error
+	SetJobPaused(id int, paused bool) error
+	CreateRun(r models.JobRun) (models.JobRun, error)
+	CompleteRun(r models.JobRun) error
+	RetrieveRuns(jobID, limit int) ([]models.JobRun, error)
+	RetrieveLastRun(jobID int) (time.Time, error)
 }
 
+// NewRegisterRepository returns a repository storing the jobs in SQLite
 func NewRegisterRepository(db *sql.DB) *RegisterRepository {
 	return &RegisterRepository{
 		db: db,
+		q:  sqliteQueries,
 	}
 }
 
@@ -58,23 +117,20 @@ func (rr *RegisterRepository) RegisterJob(j models.Job) (int, error) {
 		}
 	}()
 
-	result, err := tx.Exec(insertJob, j.Schedule.Local(), j.UserSchedule, j.Occurrences, j.Frequency, j.Label, j.CronTime)
+	var jobID int64
+	err = tx.QueryRow(rr.q.insertJob, j.Schedule.Local(), j.UserSchedule, nullString(j.Timezone), j.Occurrences, j.Frequency, j.Label, j.CronTime, j.Timeout, j.Concurrency, j.Misfire, j.MisfireLimit).Scan(&jobID)
 	if err != nil {
 		return 0, fmt.Errorf("could not insert job: %w", err)
 	}
 
-	jobID, err := result.LastInsertId()
+	err = rr.insertWorkflow(tx, jobID, j.Workflow, models.PhaseMain)
 	if err != nil {
-		return 0, fmt.Errorf("could not get last inserted job ID: %w", err)
+		return 0, err
 	}
 
-	for _, v := range j.Workflow {
-		args := strings.Join(v.Args, ",")
-
-		_, err := tx.Exec(insertTasks, jobID, v.Action, args)
-		if err != nil {
-			return 0, fmt.Errorf("could not insert tasks: %w", err)
-		}
+	err = rr.insertWorkflow(tx, jobID, j.OnFailure, models.PhaseOnFailure)
+	if err != nil {
+		return 0, err
 	}
 
 	err = tx.Commit()
@@ -87,38 +143,67 @@ func (rr *RegisterRepository) RegisterJob(j models.Job) (int, error) {
 
 func (rr *RegisterRepository) RetrieveJob(id int) (models.Job, error) {
 	var j models.Job
-	rows, err := rr.db.Query(getJob, id)
+	found := false
+
+	rows, err := rr.db.Query(rr.q.getJob, id)
 	if err != nil {
 		return models.Job{}, fmt.Errorf("could not retrieve job: %w", err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var task models.Task
+		var taskID sql.NullString
 		var action sql.NullString
 		var args sql.NullString
+		var params sql.NullString
+		var dependsOn sql.NullString
+		var retry sql.NullString
+		var timeout sql.NullInt64
+		var timezone sql.NullString
+		var phase sql.NullString
+		var condition sql.NullString
 
 		err := rows.Scan(
 			&j.ID,
 			&j.Schedule,
 			&j.UserSchedule,
+			&timezone,
 			&j.Occurrences,
 			&j.Frequency,
 			&j.Label,
+			&j.Paused,
+			&j.Timeout,
+			&j.Concurrency,
+			&j.Misfire,
+			&j.MisfireLimit,
 			&j.CreatedAt,
 
+			&taskID,
 			&action,
 			&args,
+			&params,
+			&dependsOn,
+			&retry,
+			&timeout,
+			&phase,
+			&condition,
 		)
 		if err != nil {
 			return models.Job{}, fmt.Errorf("could not scan job row: %w", err)
 		}
+		found = true
+		j.Timezone = timezone.String
 
 		if action.Valid {
-			task.Action = action.String
-			task.Args = strings.Split(args.String, ",")
-			task.JobID = j.ID
-			j.Workflow = append(j.Workflow, task)
+			task, err := scanTask(j.ID, taskID, action.String, args, params, dependsOn, retry, condition, timeout)
+			if err != nil {
+				return models.Job{}, err
+			}
+			if models.WorkflowPhase(phase.String) == models.PhaseOnFailure {
+				j.OnFailure = append(j.OnFailure, task)
+			} else {
+				j.Workflow = append(j.Workflow, task)
+			}
 		}
 	}
 
@@ -126,20 +211,24 @@ func (rr *RegisterRepository) RetrieveJob(id int) (models.Job, error) {
 		return models.Job{}, fmt.Errorf("error during rows iteration: %w", err)
 	}
 
-	if len(j.Workflow) == 0 {
-		return models.Job{}, fmt.Errorf("no job found with id: %d", id)
+	if !found {
+		return models.Job{}, fmt.Errorf("%w with id: %d", ErrJobNotFound, id)
 	}
+	j.IsOneTime = j.Occurrences == 1
 
 	return j, nil
 }
 
 func (rr *RegisterRepository) DeleteJob(id int) error {
-	_, err := rr.db.Exec(deleteJob, id)
+	result, err := rr.db.Exec(rr.q.deleteJob, id)
 	if err != nil {
 		return fmt.Errorf("could not delete job: %w", err)
 	}
 
-	log.Print("deleted")
+	err = checkJobAffected(result, id)
+	if err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -147,11 +236,11 @@ func (rr *RegisterRepository) DeleteJob(id int) error {
 func (rr *RegisterRepository) DecrementJobOccurrences(id int) (int, error) {
 	var occurrencesLeft int
 
-	row := rr.db.QueryRow(decrementOccurrences, id)
+	row := rr.db.QueryRow(rr.q.decrementOccurrences, id)
 	err := row.Scan(&occurrencesLeft)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return 0, fmt.Errorf("no job found with id: %d", id)
+			return 0, fmt.Errorf("%w with id: %d", ErrJobNotFound, id)
 		}
 		return 0, fmt.Errorf("could not update occurrences: %w", err)
 
@@ -164,7 +253,7 @@ func (rr *RegisterRepository) RetrieveJobs() ([]models.Job, error) {
 	jobMap := make(map[int]*models.Job)
 	var jobs []models.Job
 
-	rows, err := rr.db.Query(getJobs)
+	rows, err := rr.db.Query(rr.q.getJobs)
 	if err != nil {
 		return nil, fmt.Errorf("could not retrieve jobs: %w", err)
 	}
@@ -172,40 +261,63 @@ func (rr *RegisterRepository) RetrieveJobs() ([]models.Job, error) {
 
 	for rows.Next() {
 		var j models.Job
-		var t models.Task
+		var taskID sql.NullString
 		var action sql.NullString
 		var args sql.NullString
+		var params sql.NullString
+		var dependsOn sql.NullString
+		var retry sql.NullString
+		var timeout sql.NullInt64
+		var timezone sql.NullString
+		var phase sql.NullString
+		var condition sql.NullString
 
 		err := rows.Scan(
 			&j.ID,
 			&j.Schedule,
 			&j.UserSchedule,
+			&timezone,
 			&j.Occurrences,
 			&j.Frequency,
 			&j.Label,
+			&j.Paused,
+			&j.Timeout,
+			&j.Concurrency,
+			&j.Misfire,
+			&j.MisfireLimit,
 			&j.CreatedAt,
+			&taskID,
 			&action,
 			&args,
+			&params,
+			&dependsOn,
+			&retry,
+			&timeout,
+			&phase,
+			&condition,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("could not retrieve jobs: %w", err)
 		}
+		j.IsOneTime = j.Occurrences == 1
+		j.Timezone = timezone.String
 
-		if existingJob, exists := jobMap[j.ID]; exists {
-			if action.Valid {
-				t.Action = action.String
-				t.Args = strings.Split(args.String, ",")
-				t.JobID = j.ID
-				existingJob.Workflow = append(existingJob.Workflow, t)
+		existingJob, exists := jobMap[j.ID]
+		if !exists {
+			existingJob = &j
+			jobMap[j.ID] = existingJob
+		}
+
+		if action.Valid {
+			t, err := scanTask(j.ID, taskID, action.String, args, params, dependsOn, retry, condition, timeout)
+			if err != nil {
+				return nil, err
 			}
-		} else {
-			if action.Valid {
-				t.Action = action.String
-				t.Args = strings.Split(args.String, ",")
-				t.JobID = j.ID
-				j.Workflow = append(j.Workflow, t)
+			if models.WorkflowPhase(phase.String) == models.PhaseOnFailure {
+				existingJob.OnFailure = append(existingJob.OnFailure, t)
+			} else {
+				existingJob.Workflow = append(existingJob.Workflow, t)
 			}
-			jobMap[j.ID] = &j
 		}
 	}
 
@@ -218,9 +330,165 @@ func (rr *RegisterRepository) RetrieveJobs() ([]models.Job, error) {
 	}
 
 	if len(jobs) == 0 {
-		log.Print("no jobs")
 		return nil, nil
 	}
 
 	return jobs, nil
 }
+
+// UpdateJob replaces a job and its workflow
+func (rr *RegisterRepository) UpdateJob(j models.Job) error {
+	tx, err := rr.db.Begin()
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	result, err := tx.Exec(rr.q.updateJob, j.Schedule.Local(), j.UserSchedule, nullString(j.Timezone), j.Occurrences, j.Frequency, j.Label, j.CronTime, j.Timeout, j.Concurrency, j.Misfire, j.MisfireLimit, j.ID)
+	if err != nil {
+		return fmt.Errorf("could not update job: %w", err)
+	}
+
+	err = checkJobAffected(result, j.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(rr.q.deleteTasks, j.ID)
+	if err != nil {
+		return fmt.Errorf("could not delete tasks: %w", err)
+	}
+
+	err = rr.insertWorkflow(tx, int64(j.ID), j.Workflow, models.PhaseMain)
+	if err != nil {
+		return err
+	}
+
+	err = rr.insertWorkflow(tx, int64(j.ID), j.OnFailure, models.PhaseOnFailure)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("could not commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// SetJobPaused pauses or resumes a job
+func (rr *RegisterRepository) SetJobPaused(id int, paused bool) error {
+	result, err := rr.db.Exec(rr.q.updateJobPaused, paused, id)
+	if err != nil {
+		return fmt.Errorf("could not update job: %w", err)
+	}
+
+	return checkJobAffected(result, id)
+}
+
+// insertWorkflow saves the tasks of a workflow of a job
+func (rr *RegisterRepository) insertWorkflow(tx *sql.Tx, jobID int64, tasks []models.Task, phase models.WorkflowPhase) error {
+	for _, v := range tasks {
+		params, err := json.Marshal(v.Params)
+		if err != nil {
+			return fmt.Errorf("could not encode params: %w", err)
+		}
+
+		var dependsOn sql.NullString
+		if len(v.DependsOn) > 0 {
+			b, err := json.Marshal(v.DependsOn)
+			if err != nil {
+				return fmt.Errorf("could not encode dependencies: %w", err)
+			}
+			dependsOn = sql.NullString{String: string(b), Valid: true}
+		}
+
+		var retry sql.NullString
+		if v.Retry != nil {
+			b, err := json.Marshal(v.Retry)
+			if err != nil {
+				return fmt.Errorf("could not encode retry policy: %w", err)
+			}
+			retry = sql.NullString{String: string(b), Valid: true}
+		}
+
+		var condition sql.NullString
+		if v.When != nil {
+			b, err := json.Marshal(v.When)
+			if err != nil {
+				return fmt.Errorf("could not encode condition: %w", err)
+			}
+			condition = sql.NullString{String: string(b), Valid: true}
+		}
+
+		_, err = tx.Exec(rr.q.insertTasks, jobID, nullString(v.ID), v.Action, string(params), dependsOn, retry, v.Timeout, phase, condition)
+		if err != nil {
+			return fmt.Errorf("could not insert tasks: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// scanTask builds a task from its workflows row,
+// rows saved before params were introduced only have comma separated args
+func scanTask(jobID int, taskID sql.NullString, action string, args, params, dependsOn, retry, condition sql.NullString, timeout sql.NullInt64) (models.Task, error) {
+	t := models.Task{
+		ID:      taskID.String,
+		JobID:   jobID,
+		Action:  action,
+		Timeout: models.Duration(timeout.Int64),
+	}
+
+	if params.Valid {
+		err := json.Unmarshal([]byte(params.String), &t.Params)
+		if err != nil {
+			return models.Task{}, fmt.Errorf("could not decode params: %w", err)
+		}
+	} else if args.Valid {
+		t.Args = strings.Split(args.String, ",")
+	}
+
+	if dependsOn.Valid {
+		err := json.Unmarshal([]byte(dependsOn.String), &t.DependsOn)
+		if err != nil {
+			return models.Task{}, fmt.Errorf("could not decode dependencies: %w", err)
+		}
+	}
+
+	if retry.Valid {
+		err := json.Unmarshal([]byte(retry.String), &t.Retry)
+		if err != nil {
+			return models.Task{}, fmt.Errorf("could not decode retry policy: %w", err)
+		}
+	}
+
+	if condition.Valid {
+		err := json.Unmarshal([]byte(condition.String), &t.When)
+		if err != nil {
+			return models.Task{}, fmt.Errorf("could not decode condition: %w", err)
+		}
+	}
+
+	return t, nil
+}
+
+// checkJobAffected returns ErrJobNotFound when a statement did not touch any job
+func checkJobAffected(result sql.Result, id int) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not get affected rows: %w", err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%w with id: %d", ErrJobNotFound, id)
+	}
+
+	return nil
+}
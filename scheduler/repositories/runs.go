@@ -0,0 +1,212 @@
+package repositories
+
+import (
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tobg/scheduler/models"
+)
+
+// maxCreateRunTries is the number of times a run is inserted while concurrent runs
+// of its job take its attempt number
+const maxCreateRunTries = 5
+
+//go:embed queries/insert_job_run.sql
+var insertJobRun string
+
+//go:embed queries/update_job_run.sql
+var updateJobRun string
+
+//go:embed queries/insert_task_run.sql
+var insertTaskRun string
+
+//go:embed queries/get_job_runs.sql
+var getJobRuns string
+
+//go:embed queries/get_last_run.sql
+var getLastRun string
+
+// CreateRun saves a starting run and returns it with its id and attempt number
+func (rr *RegisterRepository) CreateRun(r models.JobRun) (models.JobRun, error) {
+	for i := 0; i < maxCreateRunTries; i++ {
+		// start times are saved in UTC so that SQLite orders them as text
+		row := rr.db.QueryRow(rr.q.insertJobRun, r.JobID, r.JobID, r.Trigger, r.Status, nullTime(r.ScheduledAt), r.StartedAt.UTC())
+		err := row.Scan(&r.ID, &r.Attempt)
+		// no row is inserted when a concurrent run took the attempt number
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return models.JobRun{}, fmt.Errorf("could not insert run: %w", err)
+		}
+
+		return r, nil
+	}
+
+	return models.JobRun{}, fmt.Errorf("could not insert run: attempt number of job %d still taken after %d tries", r.JobID, maxCreateRunTries)
+}
+
+// CompleteRun saves the outcome of a run and of each of its tasks
+func (rr *RegisterRepository) CompleteRun(r models.JobRun) error {
+	tx, err := rr.db.Begin()
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	_, err = tx.Exec(rr.q.updateJobRun, r.Status, nullString(r.Error), nullTime(r.EndedAt), r.ID)
+	if err != nil {
+		return fmt.Errorf("could not update run: %w", err)
+	}
+
+	for i, t := range r.Tasks {
+		var outputs sql.NullString
+		if len(t.Outputs) > 0 {
+			b, err := json.Marshal(t.Outputs)
+			if err != nil {
+				return fmt.Errorf("could not encode task outputs: %w", err)
+			}
+			outputs = sql.NullString{String: string(b), Valid: true}
+		}
+
+		phase := t.Phase
+		if phase == "" {
+			phase = models.PhaseMain
+		}
+
+		_, err = tx.Exec(rr.q.insertTaskRun, r.ID, i, phase, nullString(t.TaskID), t.Action, t.Attempt, t.Status, nullString(t.Error), outputs, nullTime(t.StartedAt), nullTime(t.EndedAt))
+		if err != nil {
+			return fmt.Errorf("could not insert task run: %w", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("could not commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// RetrieveRuns returns the limit most recent runs of a job, most recent first
+func (rr *RegisterRepository) RetrieveRuns(jobID, limit int) ([]models.JobRun, error) {
+	var runs []models.JobRun
+
+	rows, err := rr.db.Query(rr.q.getJobRuns, jobID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve runs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r models.JobRun
+		var runError sql.NullString
+		var runScheduled sql.NullTime
+		var runEnded sql.NullTime
+
+		var phase sql.NullString
+		var taskID sql.NullString
+		var action sql.NullString
+		var attempt sql.NullInt64
+		var status sql.NullString
+		var taskError sql.NullString
+		var outputs sql.NullString
+		var taskStarted sql.NullTime
+		var taskEnded sql.NullTime
+
+		err := rows.Scan(
+			&r.ID,
+			&r.JobID,
+			&r.Attempt,
+			&r.Trigger,
+			&r.Status,
+			&runError,
+			&runScheduled,
+			&r.StartedAt,
+			&runEnded,
+
+			&phase,
+			&taskID,
+			&action,
+			&attempt,
+			&status,
+			&taskError,
+			&outputs,
+			&taskStarted,
+			&taskEnded,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan run row: %w", err)
+		}
+
+		// rows are ordered by run, a new run starts when the id changes
+		if len(runs) == 0 || runs[len(runs)-1].ID != r.ID {
+			r.Error = runError.String
+			r.ScheduledAt = runScheduled.Time
+			r.EndedAt = runEnded.Time
+			runs = append(runs, r)
+		}
+
+		if action.Valid {
+			t := models.TaskRun{
+				TaskID:    taskID.String,
+				Phase:     models.WorkflowPhase(phase.String),
+				Action:    action.String,
+				Attempt:   int(attempt.Int64),
+				Status:    models.RunStatus(status.String),
+				StartedAt: taskStarted.Time,
+				EndedAt:   taskEnded.Time,
+				Error:     taskError.String,
+			}
+
+			if outputs.Valid {
+				err := json.Unmarshal([]byte(outputs.String), &t.Outputs)
+				if err != nil {
+					return nil, fmt.Errorf("could not decode task outputs: %w", err)
+				}
+			}
+
+			current := &runs[len(runs)-1]
+			current.Tasks = append(current.Tasks, t)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+
+	return runs, nil
+}
+
+// RetrieveLastRun returns the scheduled time of the last scheduled or catch up run of a job,
+// the zero time when the job never ran
+func (rr *RegisterRepository) RetrieveLastRun(jobID int) (time.Time, error) {
+	var scheduledAt sql.NullTime
+
+	err := rr.db.QueryRow(rr.q.getLastRun, jobID).Scan(&scheduledAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return time.Time{}, nil
+		}
+		return time.Time{}, fmt.Errorf("could not retrieve last run: %w", err)
+	}
+
+	return scheduledAt.Time, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
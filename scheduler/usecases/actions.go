@@ -0,0 +1,24 @@
+package usecases
+
+import (
+	"sort"
+
+	"github.com/tobg/scheduler/helpers/validations"
+	"github.com/tobg/scheduler/models"
+)
+
+// GetActions returns the schema of every available action, sorted by name
+func (ru *RegisterUsecase) GetActions() []models.ActionSchema {
+	schemas := make([]models.ActionSchema, 0, len(validations.Tasks))
+	for name, handler := range validations.Tasks {
+		schema := handler.Schema
+		schema.Action = name
+		schemas = append(schemas, schema)
+	}
+
+	sort.Slice(schemas, func(i, j int) bool {
+		return schemas[i].Action < schemas[j].Action
+	})
+
+	return schemas
+}
@@ -0,0 +1,472 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tobg/scheduler/helpers"
+	"github.com/tobg/scheduler/models"
+)
+
+// DefaultMaxParallelTasks is the number of tasks of a workflow running at the same time
+// when the executor is not given a limit
+const DefaultMaxParallelTasks = 4
+
+// Executor runs job workflows through the registered task handlers
+type Executor struct {
+	tasks       map[string]models.TaskHandler
+	maxParallel int
+}
+
+// NewExecutor returns an executor bound to the given task handlers,
+// running at most maxParallel tasks of a workflow at the same time
+func NewExecutor(tasks map[string]models.TaskHandler, maxParallel int) *Executor {
+	if maxParallel < 1 {
+		maxParallel = DefaultMaxParallelTasks
+	}
+
+	return &Executor{
+		tasks:       tasks,
+		maxParallel: maxParallel,
+	}
+}
+
+// taskResult is the outcome of a task of a workflow
+type taskResult struct {
+	position int
+	attempts []models.TaskRun
+	err      error
+}
+
+// ExecuteWorkflow runs the tasks of a job and records the outcome in the run.
+// A task starts once all its dependencies ended and its conditions are met, by default
+// when its dependencies succeeded. Independent tasks run in parallel up to the executor
+// limit and the tasks whose conditions are not met are recorded as skipped.
+// Each attempt of a retried task is recorded, in the order of the workflow.
+// The job timeout, if any, bounds the whole workflow and the task timeout each attempt.
+// When the workflow fails the on failure workflow of the job runs, outside of the job timeout.
+func (e *Executor) ExecuteWorkflow(ctx context.Context, j *models.Job, run *models.JobRun) {
+	if run.StartedAt.IsZero() {
+		run.StartedAt = time.Now()
+	}
+	run.Status = models.RunStatusSuccess
+
+	workflowCtx := ctx
+	if j.Timeout > 0 {
+		var cancel context.CancelFunc
+		workflowCtx, cancel = context.WithTimeout(ctx, time.Duration(j.Timeout))
+		defer cancel()
+	}
+
+	// the weekdays of the conditions are the days of the job timezone, validated on registration
+	location, err := helpers.LoadLocation(j.Timezone)
+	if err != nil {
+		location = time.Local
+	}
+
+	outputs := make(map[string]models.TaskOutputs)
+
+	tasks, err := e.runWorkflow(workflowCtx, j.Workflow, models.PhaseMain, run, location, outputs)
+	run.Tasks = append(run.Tasks, tasks...)
+	if err != nil {
+		run.Status = failureStatus(err)
+		run.Error = err.Error()
+	}
+
+	if run.Status != models.RunStatusSuccess && len(j.OnFailure) > 0 {
+		slog.Info("running on failure workflow", "job", j.ID)
+
+		tasks, err := e.runWorkflow(ctx, j.OnFailure, models.PhaseOnFailure, run, location, outputs)
+		run.Tasks = append(run.Tasks, tasks...)
+		if err != nil {
+			run.Error = fmt.Sprintf("%v, on failure %v", run.Error, err)
+		}
+	}
+
+	run.EndedAt = time.Now()
+}
+
+// runWorkflow runs the tasks of a workflow and returns their records, in the order of the
+// workflow, and the error of the first failed task. The outputs of the tasks are added to outputs.
+func (e *Executor) runWorkflow(ctx context.Context, workflow []models.Task, phase models.WorkflowPhase, run *models.JobRun, location *time.Location, outputs map[string]models.TaskOutputs) ([]models.TaskRun, error) {
+	deps := helpers.Dependencies(workflow)
+	pending := make([]int, len(deps))
+	dependents := make([][]int, len(deps))
+	var ready []int
+	for i, d := range deps {
+		pending[i] = len(d)
+		for _, p := range d {
+			dependents[p] = append(dependents[p], i)
+		}
+		if len(d) == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	results := make([]taskResult, len(workflow))
+	statuses := make([]models.RunStatus, len(workflow))
+	done := make(chan taskResult)
+	slots := make(chan struct{}, e.maxParallel)
+	running := 0
+
+	skipped := func(t models.Task) []models.TaskRun {
+		return []models.TaskRun{{TaskID: t.ID, Phase: phase, Action: t.Action, Status: models.RunStatusSkipped}}
+	}
+
+	// finish records the outcome of a task and returns the tasks it unlocks
+	finish := func(res taskResult, status models.RunStatus) []int {
+		results[res.position] = res
+		statuses[res.position] = status
+
+		if t := workflow[res.position]; status == models.RunStatusSuccess && t.ID != "" {
+			outputs[t.ID] = res.attempts[len(res.attempts)-1].Outputs
+		}
+
+		var unlocked []int
+		for _, d := range dependents[res.position] {
+			pending[d]--
+			if pending[d] == 0 {
+				unlocked = append(unlocked, d)
+			}
+		}
+		return unlocked
+	}
+
+	for {
+		for len(ready) > 0 {
+			i := ready[0]
+			ready = ready[1:]
+			t := workflow[i]
+
+			if ctx.Err() != nil {
+				// a workflow out of time or cancelled fails on the first task it could not start
+				ready = append(ready, finish(taskResult{position: i, attempts: skipped(t), err: ctx.Err()}, models.RunStatusSkipped)...)
+				continue
+			}
+
+			if !conditionMet(t.When, deps[i], statuses, run, location) {
+				ready = append(ready, finish(taskResult{position: i, attempts: skipped(t)}, models.RunStatusSkipped)...)
+				continue
+			}
+
+			t, err := e.resolveTemplates(t, run, outputs)
+			if err != nil {
+				failed := models.TaskRun{TaskID: t.ID, Phase: phase, Action: t.Action, Attempt: 1, Status: models.RunStatusFailed, Error: err.Error()}
+				ready = append(ready, finish(taskResult{position: i, attempts: []models.TaskRun{failed}, err: err}, models.RunStatusFailed)...)
+				continue
+			}
+
+			running++
+			go func(i int, t models.Task) {
+				select {
+				case slots <- struct{}{}:
+				case <-ctx.Done():
+					done <- taskResult{position: i, err: ctx.Err()}
+					return
+				}
+				defer func() { <-slots }()
+
+				attempts, err := e.runTask(ctx, t)
+				for a := range attempts {
+					attempts[a].Phase = phase
+				}
+				done <- taskResult{position: i, attempts: attempts, err: err}
+			}(i, t)
+		}
+
+		if running == 0 {
+			break
+		}
+
+		res := <-done
+		running--
+
+		status := models.RunStatusSuccess
+		if res.err != nil {
+			status = failureStatus(res.err)
+		}
+		if res.attempts == nil {
+			// cancelled while waiting for a slot
+			res.attempts = skipped(workflow[res.position])
+			status = models.RunStatusSkipped
+		}
+		ready = append(ready, finish(res, status)...)
+	}
+
+	var tasks []models.TaskRun
+	var firstErr error
+	for i, t := range workflow {
+		if statuses[i] == "" {
+			// only a dependency cycle leaves a task unreached, they are rejected on registration
+			results[i].attempts = skipped(t)
+			results[i].err = fmt.Errorf("task is part of a dependency cycle")
+		}
+
+		tasks = append(tasks, results[i].attempts...)
+
+		if results[i].err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("task %v failed: %w", t.Action, results[i].err)
+		}
+	}
+
+	return tasks, firstErr
+}
+
+// conditionMet returns wether or not a task whose dependencies ended must run,
+// the weekdays are the days of the scheduled time in the job location
+func conditionMet(c *models.Condition, deps []int, statuses []models.RunStatus, run *models.JobRun, location *time.Location) bool {
+	if c == nil {
+		return succeeded(deps, statuses)
+	}
+
+	if len(c.Weekdays) > 0 {
+		scheduled := run.ScheduledAt
+		if scheduled.IsZero() {
+			scheduled = run.StartedAt
+		}
+
+		// weekdays are validated on registration
+		day := strings.ToLower(scheduled.In(location).Weekday().String())[:3]
+		if !slices.ContainsFunc(c.Weekdays, func(w string) bool { return strings.ToLower(w) == day }) {
+			return false
+		}
+	}
+
+	switch c.Status {
+	case models.ConditionAlways:
+		return true
+	case models.ConditionFailure:
+		for _, p := range deps {
+			switch statuses[p] {
+			case models.RunStatusFailed, models.RunStatusTimedOut, models.RunStatusCancelled:
+				return true
+			}
+		}
+		return false
+	default:
+		return succeeded(deps, statuses)
+	}
+}
+
+// resolveTemplates replaces the templates in the params of a task with the outputs of the
+// tasks it depends on and the fields of the run, then verifies the resolved params
+func (e *Executor) resolveTemplates(t models.Task, run *models.JobRun, outputs map[string]models.TaskOutputs) (models.Task, error) {
+	if len(helpers.TemplateRefs(t.Params)) == 0 {
+		return t, nil
+	}
+
+	params, err := helpers.ResolveTemplates(t.Params, func(ref string) (string, error) {
+		return templateValue(ref, run, outputs)
+	})
+	if err != nil {
+		return t, models.Permanent(err)
+	}
+	t.Params = params
+
+	handler, exists := e.tasks[t.Action]
+	if exists && handler.Verify != nil {
+		err = handler.Verify(params)
+		if err != nil {
+			return t, models.Permanent(fmt.Errorf("could not verify task %v : %w", t.Action, err))
+		}
+	}
+
+	return t, nil
+}
+
+// templateValue returns the value of a template reference
+func templateValue(ref string, run *models.JobRun, outputs map[string]models.TaskOutputs) (string, error) {
+	r, err := helpers.ParseTemplateRef(ref)
+	if err != nil {
+		return "", err
+	}
+
+	if r.Task != "" {
+		taskOutputs, exists := outputs[r.Task]
+		if !exists {
+			return "", fmt.Errorf("task %v has no outputs", r.Task)
+		}
+		value, exists := taskOutputs[r.Output]
+		if !exists {
+			return "", fmt.Errorf("task %v has no output %v", r.Task, r.Output)
+		}
+		return value, nil
+	}
+
+	switch r.RunField {
+	case "id":
+		return strconv.Itoa(run.ID), nil
+	case "job_id":
+		return strconv.Itoa(run.JobID), nil
+	case "attempt":
+		return strconv.Itoa(run.Attempt), nil
+	case "trigger":
+		return string(run.Trigger), nil
+	case "scheduled_time":
+		return run.ScheduledAt.Format(time.RFC3339), nil
+	case "started_at":
+		return run.StartedAt.Format(time.RFC3339), nil
+	case "status":
+		return string(run.Status), nil
+	case "error":
+		return run.Error, nil
+	default:
+		return "", fmt.Errorf("unknown run field: %v", r.RunField)
+	}
+}
+
+// succeeded returns wether or not all the given tasks succeeded
+func succeeded(positions []int, statuses []models.RunStatus) bool {
+	for _, p := range positions {
+		if statuses[p] != models.RunStatusSuccess {
+			return false
+		}
+	}
+	return true
+}
+
+// runTask executes a task, retries it according to its retry policy
+// and returns a record of every attempt
+func (e *Executor) runTask(ctx context.Context, t models.Task) ([]models.TaskRun, error) {
+	var attempts []models.TaskRun
+
+	for attempt := 1; ; attempt++ {
+		tr := models.TaskRun{
+			TaskID:    t.ID,
+			Action:    t.Action,
+			Attempt:   attempt,
+			StartedAt: time.Now(),
+		}
+
+		outputs, err := e.executeTask(ctx, t)
+		tr.EndedAt = time.Now()
+		tr.Outputs = outputs
+
+		if err == nil {
+			tr.Status = models.RunStatusSuccess
+			return append(attempts, tr), nil
+		}
+
+		tr.Status = failureStatus(err)
+		tr.Error = err.Error()
+		attempts = append(attempts, tr)
+
+		// the job itself is out of time or cancelled, retrying is pointless
+		if ctx.Err() != nil || !shouldRetry(t.Retry, attempt, err) {
+			return attempts, err
+		}
+
+		delay := retryDelay(t.Retry, attempt)
+		slog.Warn("task attempt failed, retrying", "action", t.Action, "attempt", attempt, "delay", delay, "error", err)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return attempts, ctx.Err()
+		}
+	}
+}
+
+// executeTask runs a single attempt of a task and returns its outputs. The attempt is
+// abandoned once its context is done, even if the task does not honour the context itself.
+func (e *Executor) executeTask(ctx context.Context, t models.Task) (models.TaskOutputs, error) {
+	handler, exists := e.tasks[t.Action]
+	if !exists {
+		return nil, models.Permanent(fmt.Errorf("task %v does not exist", t.Action))
+	}
+
+	if handler.Execute == nil {
+		return nil, models.Permanent(fmt.Errorf("task %v has no execute function", t.Action))
+	}
+
+	// tasks registered before params were introduced only have args
+	params := t.Params
+	if params == nil && t.Args != nil && handler.ParseArgs != nil {
+		var err error
+		params, err = handler.ParseArgs(t.Args)
+		if err != nil {
+			return nil, models.Permanent(fmt.Errorf("could not convert args of task %v: %w", t.Action, err))
+		}
+	}
+
+	if t.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(t.Timeout))
+		defer cancel()
+	}
+
+	type result struct {
+		outputs models.TaskOutputs
+		err     error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		outputs, err := handler.Execute(ctx, params)
+		done <- result{outputs: outputs, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.outputs, res.err
+	case <-ctx.Done():
+		slog.Warn("task abandoned", "action", t.Action, "error", ctx.Err())
+		return nil, ctx.Err()
+	}
+}
+
+// failureStatus returns the status matching the error of a failed execution
+func failureStatus(err error) models.RunStatus {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return models.RunStatusTimedOut
+	case errors.Is(err, context.Canceled):
+		return models.RunStatusCancelled
+	default:
+		return models.RunStatusFailed
+	}
+}
+
+// shouldRetry returns wether or not a failed attempt must be retried
+func shouldRetry(p *models.RetryPolicy, attempt int, err error) bool {
+	if p == nil || attempt >= p.MaxAttempts || models.IsPermanent(err) {
+		return false
+	}
+
+	if len(p.RetryOn) == 0 {
+		return true
+	}
+
+	for _, pattern := range p.RetryOn {
+		// patterns are validated on registration
+		matched, _ := regexp.MatchString(pattern, err.Error())
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// retryDelay returns the delay to wait after a failed attempt
+func retryDelay(p *models.RetryPolicy, attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+
+	delay := float64(p.InitialDelay) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		return time.Duration(p.MaxDelay)
+	}
+
+	return time.Duration(delay)
+}
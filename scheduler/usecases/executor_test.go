@@ -0,0 +1,497 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tobg/scheduler/models"
+)
+
+func TestExecuteWorkflow(t *testing.T) {
+	tasks := map[string]models.TaskHandler{
+		"ok": {
+			Execute: func(ctx context.Context, params models.Params) (models.TaskOutputs, error) { return nil, nil },
+		},
+		"ko": {
+			Execute: func(ctx context.Context, params models.Params) (models.TaskOutputs, error) {
+				return nil, errors.New("boom")
+			},
+		},
+		"noop": {
+			Execute: nil,
+		},
+	}
+
+	// a monday
+	monday := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		workflow     []models.Task
+		onFailure    []models.Task
+		wantStatus   models.RunStatus
+		wantStatuses []models.RunStatus
+	}{
+		"nominal": {
+			workflow:     []models.Task{{Action: "ok"}, {Action: "ok"}},
+			wantStatus:   models.RunStatusSuccess,
+			wantStatuses: []models.RunStatus{models.RunStatusSuccess, models.RunStatusSuccess},
+		},
+		"failing task, stop workflow": {
+			workflow:     []models.Task{{Action: "ok"}, {Action: "ko"}, {Action: "ok"}},
+			wantStatus:   models.RunStatusFailed,
+			wantStatuses: []models.RunStatus{models.RunStatusSuccess, models.RunStatusFailed, models.RunStatusSkipped},
+		},
+		"dag, all branches run": {
+			workflow: []models.Task{
+				{ID: "build", Action: "ok"},
+				{ID: "test", Action: "ok", DependsOn: []string{"build"}},
+				{ID: "lint", Action: "ok", DependsOn: []string{"build"}},
+				{ID: "deploy", Action: "ok", DependsOn: []string{"test", "lint"}},
+			},
+			wantStatus:   models.RunStatusSuccess,
+			wantStatuses: []models.RunStatus{models.RunStatusSuccess, models.RunStatusSuccess, models.RunStatusSuccess, models.RunStatusSuccess},
+		},
+		"dag, failing branch skips its downstream tasks only": {
+			workflow: []models.Task{
+				{ID: "build", Action: "ok"},
+				{ID: "test", Action: "ko", DependsOn: []string{"build"}},
+				{ID: "docs", Action: "ok", DependsOn: []string{"build"}},
+				{ID: "deploy", Action: "ok", DependsOn: []string{"test"}},
+				{ID: "notify", Action: "ok", DependsOn: []string{"deploy"}},
+			},
+			wantStatus: models.RunStatusFailed,
+			wantStatuses: []models.RunStatus{
+				models.RunStatusSuccess, models.RunStatusFailed, models.RunStatusSuccess,
+				models.RunStatusSkipped, models.RunStatusSkipped,
+			},
+		},
+		"failure condition, run on failed dependency only": {
+			workflow: []models.Task{
+				{ID: "deploy", Action: "ko"},
+				{ID: "rollback", Action: "ok", DependsOn: []string{"deploy"}, When: &models.Condition{Status: models.ConditionFailure}},
+				{ID: "notify", Action: "ok", DependsOn: []string{"deploy"}},
+			},
+			wantStatus:   models.RunStatusFailed,
+			wantStatuses: []models.RunStatus{models.RunStatusFailed, models.RunStatusSuccess, models.RunStatusSkipped},
+		},
+		"failure condition, skip on success": {
+			workflow: []models.Task{
+				{ID: "deploy", Action: "ok"},
+				{ID: "rollback", Action: "ok", DependsOn: []string{"deploy"}, When: &models.Condition{Status: models.ConditionFailure}},
+			},
+			wantStatus:   models.RunStatusSuccess,
+			wantStatuses: []models.RunStatus{models.RunStatusSuccess, models.RunStatusSkipped},
+		},
+		"always condition, run after failure": {
+			workflow: []models.Task{
+				{ID: "deploy", Action: "ko"},
+				{ID: "cleanup", Action: "ok", DependsOn: []string{"deploy"}, When: &models.Condition{Status: models.ConditionAlways}},
+			},
+			wantStatus:   models.RunStatusFailed,
+			wantStatuses: []models.RunStatus{models.RunStatusFailed, models.RunStatusSuccess},
+		},
+		"weekdays condition": {
+			workflow: []models.Task{
+				{ID: "weekday", Action: "ok", When: &models.Condition{Weekdays: []string{"mon", "tue"}}},
+				{ID: "weekend", Action: "ok", When: &models.Condition{Weekdays: []string{"sat", "sun"}}},
+				{ID: "after", Action: "ok", DependsOn: []string{"weekend"}},
+			},
+			wantStatus:   models.RunStatusSuccess,
+			wantStatuses: []models.RunStatus{models.RunStatusSuccess, models.RunStatusSkipped, models.RunStatusSkipped},
+		},
+		"on failure workflow, run when workflow fails": {
+			workflow:   []models.Task{{ID: "deploy", Action: "ko"}},
+			onFailure:  []models.Task{{ID: "rollback", Action: "ok"}, {ID: "notify", Action: "ok"}},
+			wantStatus: models.RunStatusFailed,
+			wantStatuses: []models.RunStatus{
+				models.RunStatusFailed, models.RunStatusSuccess, models.RunStatusSuccess,
+			},
+		},
+		"on failure workflow, not run on success": {
+			workflow:     []models.Task{{ID: "deploy", Action: "ok"}},
+			onFailure:    []models.Task{{ID: "rollback", Action: "ok"}},
+			wantStatus:   models.RunStatusSuccess,
+			wantStatuses: []models.RunStatus{models.RunStatusSuccess},
+		},
+		"unknown task, fail": {
+			workflow:     []models.Task{{Action: "unknown"}},
+			wantStatus:   models.RunStatusFailed,
+			wantStatuses: []models.RunStatus{models.RunStatusFailed},
+		},
+		"no execute function, fail": {
+			workflow:     []models.Task{{Action: "noop"}},
+			wantStatus:   models.RunStatusFailed,
+			wantStatuses: []models.RunStatus{models.RunStatusFailed},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ex := NewExecutor(tasks, DefaultMaxParallelTasks)
+			run := models.JobRun{ScheduledAt: monday}
+			ex.ExecuteWorkflow(context.Background(), &models.Job{ID: 1, Workflow: tt.workflow, OnFailure: tt.onFailure}, &run)
+
+			assert.Equal(t, tt.wantStatus, run.Status)
+			assert.Len(t, run.Tasks, len(tt.wantStatuses))
+			for i, s := range tt.wantStatuses {
+				assert.Equal(t, s, run.Tasks[i].Status)
+			}
+		})
+	}
+}
+
+func TestExecuteWorkflowWeekdaysTimezone(t *testing.T) {
+	tasks := map[string]models.TaskHandler{
+		"ok": {
+			Execute: func(ctx context.Context, params models.Params) (models.TaskOutputs, error) { return nil, nil },
+		},
+	}
+
+	// monday 20:00 UTC is already tuesday 05:00 in Tokyo
+	mondayEvening := time.Date(2024, time.January, 1, 20, 0, 0, 0, time.UTC)
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		timezone     string
+		scheduledAt  time.Time
+		wantStatuses []models.RunStatus
+	}{
+		"utc job, monday": {
+			timezone:     "UTC",
+			scheduledAt:  mondayEvening,
+			wantStatuses: []models.RunStatus{models.RunStatusSuccess, models.RunStatusSuccess, models.RunStatusSkipped},
+		},
+		"tokyo job, tuesday in tokyo": {
+			timezone:     "Asia/Tokyo",
+			scheduledAt:  mondayEvening,
+			wantStatuses: []models.RunStatus{models.RunStatusSuccess, models.RunStatusSkipped, models.RunStatusSuccess},
+		},
+		"utc job scheduled in tokyo time, monday in utc": {
+			timezone:     "UTC",
+			scheduledAt:  mondayEvening.In(tokyo),
+			wantStatuses: []models.RunStatus{models.RunStatusSuccess, models.RunStatusSuccess, models.RunStatusSkipped},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			workflow := []models.Task{
+				{ID: "start", Action: "ok"},
+				{ID: "monday", Action: "ok", DependsOn: []string{"start"}, When: &models.Condition{Weekdays: []string{"mon"}}},
+				{ID: "tuesday", Action: "ok", DependsOn: []string{"start"}, When: &models.Condition{Weekdays: []string{"tue"}}},
+			}
+
+			ex := NewExecutor(tasks, DefaultMaxParallelTasks)
+			run := models.JobRun{ScheduledAt: tt.scheduledAt}
+			ex.ExecuteWorkflow(context.Background(), &models.Job{ID: 1, Timezone: tt.timezone, Workflow: workflow}, &run)
+
+			require.Len(t, run.Tasks, len(tt.wantStatuses))
+			for i, s := range tt.wantStatuses {
+				assert.Equal(t, s, run.Tasks[i].Status, workflow[i].ID)
+			}
+		})
+	}
+}
+
+func TestExecuteWorkflowOnFailure(t *testing.T) {
+	var got models.Params
+	ex := NewExecutor(map[string]models.TaskHandler{
+		"ko": {
+			Execute: func(ctx context.Context, params models.Params) (models.TaskOutputs, error) {
+				return models.TaskOutputs{"release": "42"}, errors.New("boom")
+			},
+		},
+		"ok": {
+			Execute: func(ctx context.Context, params models.Params) (models.TaskOutputs, error) {
+				return models.TaskOutputs{"release": "41"}, nil
+			},
+		},
+		"notify": {
+			Execute: func(ctx context.Context, params models.Params) (models.TaskOutputs, error) {
+				got = params
+				return nil, errors.New("unreachable")
+			},
+		},
+	}, DefaultMaxParallelTasks)
+
+	j := &models.Job{
+		ID: 1,
+		Workflow: []models.Task{
+			{ID: "current", Action: "ok"},
+			{ID: "deploy", Action: "ko", DependsOn: []string{"current"}},
+		},
+		OnFailure: []models.Task{
+			{ID: "notify", Action: "notify", Params: models.Params{
+				"text": "{{ run.status }}: {{ run.error }}, back to {{ tasks.current.outputs.release }}",
+			}},
+		},
+	}
+
+	var run models.JobRun
+	ex.ExecuteWorkflow(context.Background(), j, &run)
+
+	assert.Equal(t, models.RunStatusFailed, run.Status)
+	assert.Equal(t, "failed: task ko failed: boom, back to 41", got["text"])
+	assert.Equal(t, "task ko failed: boom, on failure task notify failed: unreachable", run.Error)
+
+	if assert.Len(t, run.Tasks, 3) {
+		assert.Equal(t, models.PhaseMain, run.Tasks[1].Phase)
+		assert.Equal(t, models.PhaseOnFailure, run.Tasks[2].Phase)
+		assert.Equal(t, models.RunStatusFailed, run.Tasks[2].Status)
+	}
+}
+
+func TestExecuteWorkflowParallel(t *testing.T) {
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+
+	ex := NewExecutor(map[string]models.TaskHandler{
+		"slow": {
+			Execute: func(ctx context.Context, params models.Params) (models.TaskOutputs, error) {
+				mu.Lock()
+				running++
+				maxRunning = max(maxRunning, running)
+				mu.Unlock()
+
+				time.Sleep(20 * time.Millisecond)
+
+				mu.Lock()
+				running--
+				mu.Unlock()
+				return nil, nil
+			},
+		},
+	}, 2)
+
+	job := &models.Job{ID: 1, Workflow: []models.Task{
+		{ID: "a", Action: "slow"},
+		{ID: "b", Action: "slow"},
+		{ID: "c", Action: "slow"},
+		{ID: "d", Action: "slow", DependsOn: []string{"a", "b", "c"}},
+	}}
+
+	var run models.JobRun
+	ex.ExecuteWorkflow(context.Background(), job, &run)
+
+	assert.Equal(t, models.RunStatusSuccess, run.Status)
+	assert.Equal(t, 2, maxRunning)
+	for i, tr := range run.Tasks {
+		assert.Equal(t, job.Workflow[i].ID, tr.TaskID)
+	}
+}
+
+func TestExecuteWorkflowTemplates(t *testing.T) {
+	var received models.Params
+
+	ex := NewExecutor(map[string]models.TaskHandler{
+		"build": {
+			Execute: func(ctx context.Context, params models.Params) (models.TaskOutputs, error) {
+				return models.TaskOutputs{"version": "1.4.2"}, nil
+			},
+		},
+		"deploy": {
+			Execute: func(ctx context.Context, params models.Params) (models.TaskOutputs, error) {
+				received = params
+				return nil, nil
+			},
+		},
+	}, DefaultMaxParallelTasks)
+
+	tests := map[string]struct {
+		params       models.Params
+		wantStatus   models.RunStatus
+		wantReceived models.Params
+	}{
+		"nominal": {
+			params:       models.Params{"artifact": "/tmp/app-{{ tasks.build.outputs.version }}.tar.gz", "at": "{{ run.scheduled_time }}"},
+			wantStatus:   models.RunStatusSuccess,
+			wantReceived: models.Params{"artifact": "/tmp/app-1.4.2.tar.gz", "at": "2026-10-17T02:00:00Z"},
+		},
+		"missing output, fail": {
+			params:     models.Params{"artifact": "{{ tasks.build.outputs.release }}"},
+			wantStatus: models.RunStatusFailed,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			received = nil
+			job := &models.Job{ID: 1, Workflow: []models.Task{
+				{ID: "build", Action: "build"},
+				{ID: "deploy", Action: "deploy", Params: tt.params},
+			}}
+			run := models.JobRun{ScheduledAt: time.Date(2026, 10, 17, 2, 0, 0, 0, time.UTC)}
+
+			ex.ExecuteWorkflow(context.Background(), job, &run)
+
+			assert.Equal(t, tt.wantStatus, run.Status)
+			assert.Equal(t, tt.wantReceived, received)
+		})
+	}
+}
+
+func TestExecuteWorkflowRetry(t *testing.T) {
+	tests := map[string]struct {
+		failures     int
+		err          error
+		retry        *models.RetryPolicy
+		wantStatus   models.RunStatus
+		wantAttempts int
+	}{
+		"no policy, no retry": {
+			failures:     1,
+			err:          errors.New("connection refused"),
+			wantStatus:   models.RunStatusFailed,
+			wantAttempts: 1,
+		},
+		"nominal, succeed on retry": {
+			failures:     2,
+			err:          errors.New("connection refused"),
+			retry:        &models.RetryPolicy{MaxAttempts: 3, InitialDelay: models.Duration(time.Millisecond)},
+			wantStatus:   models.RunStatusSuccess,
+			wantAttempts: 3,
+		},
+		"attempts exhausted, fail": {
+			failures:     5,
+			err:          errors.New("connection refused"),
+			retry:        &models.RetryPolicy{MaxAttempts: 2, InitialDelay: models.Duration(time.Millisecond)},
+			wantStatus:   models.RunStatusFailed,
+			wantAttempts: 2,
+		},
+		"permanent error, no retry": {
+			failures:     1,
+			err:          models.Permanent(errors.New("invalid artifact")),
+			retry:        &models.RetryPolicy{MaxAttempts: 3},
+			wantStatus:   models.RunStatusFailed,
+			wantAttempts: 1,
+		},
+		"error not matching retry_on, no retry": {
+			failures:     1,
+			err:          errors.New("permission denied"),
+			retry:        &models.RetryPolicy{MaxAttempts: 3, RetryOn: []string{"connection (refused|reset)"}},
+			wantStatus:   models.RunStatusFailed,
+			wantAttempts: 1,
+		},
+		"error matching retry_on, retry": {
+			failures:     1,
+			err:          errors.New("connection reset by peer"),
+			retry:        &models.RetryPolicy{MaxAttempts: 3, RetryOn: []string{"connection (refused|reset)"}},
+			wantStatus:   models.RunStatusSuccess,
+			wantAttempts: 2,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			calls := 0
+			ex := NewExecutor(map[string]models.TaskHandler{
+				"flaky": {
+					Execute: func(ctx context.Context, params models.Params) (models.TaskOutputs, error) {
+						calls++
+						if calls <= tt.failures {
+							return nil, tt.err
+						}
+						return nil, nil
+					},
+				},
+			}, DefaultMaxParallelTasks)
+
+			var run models.JobRun
+			ex.ExecuteWorkflow(context.Background(), &models.Job{ID: 1, Workflow: []models.Task{{Action: "flaky", Retry: tt.retry}}}, &run)
+
+			assert.Equal(t, tt.wantStatus, run.Status)
+			assert.Len(t, run.Tasks, tt.wantAttempts)
+			for i, tr := range run.Tasks {
+				assert.Equal(t, i+1, tr.Attempt)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	p := &models.RetryPolicy{
+		MaxAttempts:  5,
+		InitialDelay: models.Duration(time.Second),
+		MaxDelay:     models.Duration(5 * time.Second),
+	}
+
+	assert.Equal(t, time.Second, retryDelay(p, 1))
+	assert.Equal(t, 2*time.Second, retryDelay(p, 2))
+	assert.Equal(t, 4*time.Second, retryDelay(p, 3))
+	assert.Equal(t, 5*time.Second, retryDelay(p, 4))
+}
+
+func TestExecuteWorkflowTimeout(t *testing.T) {
+	tasks := map[string]models.TaskHandler{
+		"ok": {
+			Execute: func(ctx context.Context, params models.Params) (models.TaskOutputs, error) { return nil, nil },
+		},
+		"slow": {
+			Execute: func(ctx context.Context, params models.Params) (models.TaskOutputs, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+		},
+		"hung": {
+			// ignores its context
+			Execute: func(ctx context.Context, params models.Params) (models.TaskOutputs, error) {
+				time.Sleep(time.Second)
+				return nil, nil
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		job          models.Job
+		wantStatus   models.RunStatus
+		wantStatuses []models.RunStatus
+	}{
+		"task timeout": {
+			job: models.Job{Workflow: []models.Task{
+				{Action: "slow", Timeout: models.Duration(10 * time.Millisecond)},
+				{Action: "ok"},
+			}},
+			wantStatus:   models.RunStatusTimedOut,
+			wantStatuses: []models.RunStatus{models.RunStatusTimedOut, models.RunStatusSkipped},
+		},
+		"task ignoring its context is abandoned": {
+			job: models.Job{Workflow: []models.Task{
+				{Action: "hung", Timeout: models.Duration(10 * time.Millisecond)},
+			}},
+			wantStatus:   models.RunStatusTimedOut,
+			wantStatuses: []models.RunStatus{models.RunStatusTimedOut},
+		},
+		"job timeout": {
+			job: models.Job{
+				Timeout: models.Duration(10 * time.Millisecond),
+				Workflow: []models.Task{
+					{Action: "ok"},
+					{Action: "slow", Retry: &models.RetryPolicy{MaxAttempts: 3}},
+					{Action: "ok"},
+				},
+			},
+			wantStatus:   models.RunStatusTimedOut,
+			wantStatuses: []models.RunStatus{models.RunStatusSuccess, models.RunStatusTimedOut, models.RunStatusSkipped},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ex := NewExecutor(tasks, DefaultMaxParallelTasks)
+			var run models.JobRun
+			ex.ExecuteWorkflow(context.Background(), &tt.job, &run)
+
+			assert.Equal(t, tt.wantStatus, run.Status)
+			assert.Len(t, run.Tasks, len(tt.wantStatuses))
+			for i, s := range tt.wantStatuses {
+				assert.Equal(t, s, run.Tasks[i].Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package usecases
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/tobg/scheduler/models"
+)
+
+// GetJob returns a single job
+func (ru *RegisterUsecase) GetJob(id int) (models.Job, error) {
+	job, err := ru.rr.RetrieveJob(id)
+	if err != nil {
+		return models.Job{}, err
+	}
+	ru.setNextRun(&job)
+
+	return job, nil
+}
+
+// ParsePatch applies the fields present in the request body on top of an existing job
+func (ru *RegisterUsecase) ParsePatch(r *http.Request, j models.Job) (models.Job, error) {
+	if r.Body == nil {
+		return models.Job{}, errors.New("empty request body")
+	}
+
+	userSchedule, timezone := j.UserSchedule, j.Timezone
+
+	// a workflow in the body replaces the whole workflow instead of being merged into it
+	workflow, onFailure := j.Workflow, j.OnFailure
+	j.Workflow, j.OnFailure = nil, nil
+
+	err := json.NewDecoder(r.Body).Decode(&j)
+	if err != nil {
+		return models.Job{}, err
+	}
+
+	if j.Workflow == nil {
+		j.Workflow = workflow
+	}
+	if j.OnFailure == nil {
+		j.OnFailure = onFailure
+	}
+
+	err = convertArgs(&j)
+	if err != nil {
+		return models.Job{}, err
+	}
+
+	if j.UserSchedule != userSchedule || j.Timezone != timezone {
+		err = parseSchedule(&j)
+		if err != nil {
+			return models.Job{}, err
+		}
+	}
+	j.IsOneTime = j.Occurrences == 1
+
+	return j, nil
+}
+
+// UpdateJob replaces a job and reschedules it unless it is paused
+func (ru *RegisterUsecase) UpdateJob(id int, j models.Job) (models.Job, error) {
+	existing, err := ru.rr.RetrieveJob(id)
+	if err != nil {
+		return models.Job{}, err
+	}
+
+	j.ID = id
+	j.Paused = existing.Paused
+	j.CreatedAt = existing.CreatedAt
+
+	err = ru.SetCronFrequency(&j)
+	if err != nil {
+		return models.Job{}, fmt.Errorf("could not create cron frequency: %w", err)
+	}
+
+	err = ru.rr.UpdateJob(j)
+	if err != nil {
+		return models.Job{}, err
+	}
+
+	ru.sc.Remove(id)
+	if !j.Paused {
+		err = ru.scheduleJob(j)
+		if err != nil {
+			return models.Job{}, fmt.Errorf("could not reschedule job: %w", err)
+		}
+	}
+
+	slog.Info("job updated", "job", id)
+
+	return ru.GetJob(id)
+}
+
+// DeleteJob cancels a job and removes it from database
+func (ru *RegisterUsecase) DeleteJob(id int) error {
+	ru.sc.Remove(id)
+
+	err := ru.rr.DeleteJob(id)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("job deleted", "job", id)
+
+	return nil
+}
+
+// PauseJob cancels the pending executions of a job until it is resumed
+func (ru *RegisterUsecase) PauseJob(id int) error {
+	err := ru.rr.SetJobPaused(id, true)
+	if err != nil {
+		return err
+	}
+
+	ru.sc.Remove(id)
+	slog.Info("job paused", "job", id)
+
+	return nil
+}
+
+// ResumeJob schedules a paused job again from its next valid schedule
+func (ru *RegisterUsecase) ResumeJob(id int) (models.Job, error) {
+	err := ru.rr.SetJobPaused(id, false)
+	if err != nil {
+		return models.Job{}, err
+	}
+
+	job, err := ru.rr.RetrieveJob(id)
+	if err != nil {
+		return models.Job{}, err
+	}
+
+	err = ru.scheduleJob(job)
+	if err != nil {
+		return models.Job{}, fmt.Errorf("could not reschedule job: %w", err)
+	}
+	ru.setNextRun(&job)
+
+	slog.Info("job resumed", "job", id)
+
+	return job, nil
+}
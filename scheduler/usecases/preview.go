@@ -0,0 +1,89 @@
+package usecases
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/tobg/scheduler/helpers"
+	"github.com/tobg/scheduler/helpers/validations"
+	"github.com/tobg/scheduler/models"
+)
+
+// DefaultPreviewCount is the number of fire times of a preview when none is asked
+const DefaultPreviewCount = 10
+
+// MaxPreviewCount is the highest number of fire times of a preview
+const MaxPreviewCount = 100
+
+// GetNextRuns returns the next fire times of a job, a paused job has none
+func (ru *RegisterUsecase) GetNextRuns(j models.Job, count int) (models.SchedulePreview, error) {
+	preview := models.SchedulePreview{
+		Frequency: j.Frequency,
+		Timezone:  j.Timezone,
+		NextRuns:  []time.Time{},
+	}
+
+	err := validations.IsValidPreviewCount(count, MaxPreviewCount)
+	if err != nil {
+		return models.SchedulePreview{}, err
+	}
+
+	if j.Paused {
+		return preview, nil
+	}
+
+	preview.NextRuns, err = helpers.NextRuns(j, time.Now(), count)
+	if err != nil {
+		return models.SchedulePreview{}, fmt.Errorf("could not compute next runs: %w", err)
+	}
+
+	return preview, nil
+}
+
+// ParsePreview reads a schedule to preview from the body of the request
+func (ru *RegisterUsecase) ParsePreview(r *http.Request) (models.PreviewRequest, error) {
+	var pr models.PreviewRequest
+
+	if r.Body == nil {
+		return models.PreviewRequest{}, errors.New("empty request body")
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&pr)
+	if err != nil {
+		return models.PreviewRequest{}, err
+	}
+
+	if pr.Count == 0 {
+		pr.Count = DefaultPreviewCount
+	}
+
+	return pr, nil
+}
+
+// PreviewSchedule returns the fire times of a schedule as if a recurring job was registered with it
+func (ru *RegisterUsecase) PreviewSchedule(pr models.PreviewRequest) (models.SchedulePreview, error) {
+	err := validations.IsValidFrequency(pr.Frequency)
+	if err != nil {
+		return models.SchedulePreview{}, err
+	}
+
+	j := models.Job{
+		Frequency:   pr.Frequency,
+		Timezone:    pr.Timezone,
+		Occurrences: -1,
+		Schedule:    time.Now().Truncate(time.Minute),
+	}
+
+	if pr.Start != "" {
+		j.UserSchedule = pr.Start
+		err = parseSchedule(&j)
+		if err != nil {
+			return models.SchedulePreview{}, err
+		}
+	}
+
+	return ru.GetNextRuns(j, pr.Count)
+}
@@ -0,0 +1,62 @@
+package usecases
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tobg/scheduler/models"
+)
+
+func TestPreviewSchedule(t *testing.T) {
+	tests := map[string]struct {
+		request  models.PreviewRequest
+		wantRuns []string
+		wantErr  assert.ErrorAssertionFunc
+	}{
+		"nominal, letter frequency from start": {
+			request: models.PreviewRequest{Frequency: "W", Start: "2099-01-02T09:30:00Z", Timezone: "UTC", Count: 3},
+			wantRuns: []string{
+				"2099-01-02T09:30:00Z", "2099-01-09T09:30:00Z", "2099-01-16T09:30:00Z",
+			},
+			wantErr: assert.NoError,
+		},
+		"nominal, cron expression in timezone": {
+			request: models.PreviewRequest{Frequency: "0 9 * * 1-5", Start: "02-01-2099 08:00", Timezone: "Asia/Tokyo", Count: 3},
+			wantRuns: []string{
+				"2099-01-02T08:00:00+09:00", "2099-01-02T09:00:00+09:00", "2099-01-05T09:00:00+09:00",
+			},
+			wantErr: assert.NoError,
+		},
+		"invalid frequency, return error": {
+			request: models.PreviewRequest{Frequency: "every day", Count: 3},
+			wantErr: assert.Error,
+		},
+		"invalid timezone, return error": {
+			request: models.PreviewRequest{Frequency: "D", Timezone: "Mars/Olympus_Mons", Count: 3},
+			wantErr: assert.Error,
+		},
+		"too many runs, return error": {
+			request: models.PreviewRequest{Frequency: "D", Count: MaxPreviewCount + 1},
+			wantErr: assert.Error,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ru := NewRegisterUsecase(nil, nil)
+
+			preview, err := ru.PreviewSchedule(tt.request)
+			tt.wantErr(t, err)
+			if err != nil {
+				return
+			}
+
+			var got []string
+			for _, run := range preview.NextRuns {
+				got = append(got, run.Format(time.RFC3339))
+			}
+			assert.Equal(t, tt.wantRuns, got)
+		})
+	}
+}
@@ -1,14 +1,14 @@
 package usecases
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
-	"github.com/robfig/cron"
 	"github.com/tobg/scheduler/helpers"
 	"github.com/tobg/scheduler/helpers/validations"
 	"github.com/tobg/scheduler/models"
@@ -18,6 +18,7 @@ import (
 // RegisterUsecase represents a register controller
 type RegisterUsecase struct {
 	rr repositories.RegisterInterface
+	sc *Scheduler
 }
 
 type RegisterInterface interface {
@@ -29,12 +30,27 @@ type RegisterInterface interface {
 	CleanPayload(j *models.Job, id int)
 	GetJobs() ([]models.Job, error)
 	ReloadJobs() error
+	GetJobRuns(id, limit int) ([]models.JobRun, error)
+	GetJob(id int) (models.Job, error)
+	ParsePatch(r *http.Request, j models.Job) (models.Job, error)
+	UpdateJob(id int, j models.Job) (models.Job, error)
+	DeleteJob(id int) error
+	PauseJob(id int) error
+	ResumeJob(id int) (models.Job, error)
+	ParseTrigger(r *http.Request) (models.TriggerRequest, error)
+	ApplyTrigger(j models.Job, tr models.TriggerRequest) (models.Job, error)
+	TriggerJob(j models.Job, tr models.TriggerRequest) (models.JobRun, error)
+	GetActions() []models.ActionSchema
+	GetNextRuns(j models.Job, count int) (models.SchedulePreview, error)
+	ParsePreview(r *http.Request) (models.PreviewRequest, error)
+	PreviewSchedule(pr models.PreviewRequest) (models.SchedulePreview, error)
 }
 
 // NewRegisterUsecase returns a register usecase
-func NewRegisterUsecase(rr repositories.RegisterInterface) *RegisterUsecase {
+func NewRegisterUsecase(rr repositories.RegisterInterface, sc *Scheduler) *RegisterUsecase {
 	return &RegisterUsecase{
 		rr: rr,
+		sc: sc,
 	}
 }
 
@@ -51,22 +67,148 @@ func (ru *RegisterUsecase) ParseBody(r *http.Request) (models.Job, error) {
 		return models.Job{}, err
 	}
 
-	location, err := time.LoadLocation("Local")
+	if job.Concurrency == "" {
+		job.Concurrency = models.ConcurrencyAllow
+	}
+	if job.Misfire == "" {
+		job.Misfire = models.MisfireSkip
+	}
+
+	err = convertArgs(&job)
+	if err != nil {
+		return models.Job{}, err
+	}
+
+	err = parseSchedule(&job)
 	if err != nil {
-		return models.Job{}, errors.New("could not add local env timezone")
+		return models.Job{}, err
 	}
 
-	// Parse the Schedule string to proper time format "DD-MM-YYYY HH:MM"
-	parsedTime, err := time.ParseInLocation("02-01-2006 15:04", job.UserSchedule, location)
+	return job, nil
+}
+
+// parseSchedule sets the job schedule from the user schedule, read in the job timezone
+func parseSchedule(job *models.Job) error {
+	location, err := helpers.LoadLocation(job.Timezone)
 	if err != nil {
-		return models.Job{}, errors.New("invalid schedule format; expected DD-MM-YYYY HH:MM")
+		return err
+	}
+
+	parsedTime, err := helpers.ParseSchedule(job.UserSchedule, location)
+	if err != nil {
+		return err
 	}
 
 	// Add date in UTC time format to job
 	job.Schedule = parsedTime.UTC()
 	job.IsOneTime = job.Occurrences == 1
 
-	return job, nil
+	return nil
+}
+
+// convertArgs replaces the legacy args of the job tasks with params
+func convertArgs(job *models.Job) error {
+	for _, workflow := range [][]models.Task{job.Workflow, job.OnFailure} {
+		for i, t := range workflow {
+			if t.Args == nil {
+				continue
+			}
+
+			if t.Params != nil {
+				return fmt.Errorf("task %v has both args and params, use params only", t.Action)
+			}
+
+			params, err := validations.TaskParams(t)
+			if err != nil {
+				return fmt.Errorf("could not convert args of task %v: %w", t.Action, err)
+			}
+
+			workflow[i].Params = params
+			workflow[i].Args = nil
+		}
+	}
+
+	return nil
+}
+
+// validateTask checks the action, retry policy, timeout and condition of a task
+func validateTask(t models.Task) error {
+	err := validations.IsValidAction(t)
+	if err != nil {
+		return err
+	}
+
+	err = validations.IsValidRetryPolicy(t.Retry)
+	if err != nil {
+		return fmt.Errorf("invalid retry policy for task %v: %w", t.Action, err)
+	}
+
+	err = validations.IsValidTimeout(t.Timeout)
+	if err != nil {
+		return fmt.Errorf("invalid task %v: %w", t.Action, err)
+	}
+
+	err = validations.IsValidCondition(t.When)
+	if err != nil {
+		return fmt.Errorf("invalid condition for task %v: %w", t.Action, err)
+	}
+
+	return nil
+}
+
+// validateTasks checks the tasks of the workflow and of the on failure workflow of a job
+func validateTasks(j models.Job) error {
+	for _, v := range j.Workflow {
+		err := validateTask(v)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := validations.IsValidWorkflow(j.Workflow, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range j.OnFailure {
+		err := validateTask(v)
+		if err != nil {
+			return fmt.Errorf("invalid on_failure workflow: %w", err)
+		}
+	}
+
+	err = validations.IsValidWorkflow(j.OnFailure, j.Workflow)
+	if err != nil {
+		return fmt.Errorf("invalid on_failure workflow: %w", err)
+	}
+
+	return nil
+}
+
+// InvalidJob is a stored job whose tasks are not valid anymore, such as a legacy
+// deploy task without artifact, its runs fail before the task starts
+type InvalidJob struct {
+	ID    int
+	Label string
+	Err   error
+}
+
+// CheckJobs returns the stored jobs whose tasks would be rejected on registration
+func CheckJobs(rr repositories.RegisterInterface) ([]InvalidJob, error) {
+	jobs, err := rr.RetrieveJobs()
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve jobs: %w", err)
+	}
+
+	var invalid []InvalidJob
+	for _, j := range jobs {
+		err := validateTasks(j)
+		if err != nil {
+			invalid = append(invalid, InvalidJob{ID: j.ID, Label: j.Label, Err: err})
+		}
+	}
+
+	return invalid, nil
 }
 
 // ValidateJob checks if the job is valid
@@ -81,11 +223,29 @@ func (ru *RegisterUsecase) ValidateJob(j models.Job) error {
 		return err
 	}
 
-	for _, v := range j.Workflow {
-		err := validations.IsValidAction(v)
-		if err != nil {
-			return err
-		}
+	err = validateTasks(j)
+	if err != nil {
+		return err
+	}
+
+	err = validations.IsValidTimeout(j.Timeout)
+	if err != nil {
+		return err
+	}
+
+	err = validations.IsValidConcurrencyPolicy(j.Concurrency)
+	if err != nil {
+		return err
+	}
+
+	_, err = helpers.LoadLocation(j.Timezone)
+	if err != nil {
+		return err
+	}
+
+	err = validations.IsValidMisfirePolicy(j.Misfire, j.MisfireLimit)
+	if err != nil {
+		return err
 	}
 
 	if j.Label == "" {
@@ -121,6 +281,9 @@ func (ru *RegisterUsecase) CleanPayload(j *models.Job, id int) {
 	for i := range j.Workflow {
 		j.Workflow[i].JobID = id // Assuming Task struct has JobID field
 	}
+	for i := range j.OnFailure {
+		j.OnFailure[i].JobID = id
+	}
 }
 
 // RegisterJob registers a new job
@@ -136,26 +299,16 @@ func (ru *RegisterUsecase) RegisterJob(j models.Job, timeUntilStart time.Duratio
 		}
 		j.ID = jobID
 	} else {
-		// To ensure we have a cronFrequency on reload job
-		// even if the job has been cut before triggering once.
-		err := ru.SetCronFrequency(&j)
-		if err != nil {
-			return 0, fmt.Errorf("could not reset cron frequency")
-		}
-
-		// it's a reload we already have it in db
+		// it's a reload we already have it in db, its cron frequency is set by scheduleJob
 		jobID = j.ID
 	}
 
 	if timeUntilStart > 0 {
-		// register a go routine that'll trigger at job start time
-		time.AfterFunc(timeUntilStart, func() {
-			log.Printf("registering job: %v", j.ID)
-			err := handleJob(&j, ru.rr)
-			if err != nil {
-				log.Printf("could not register job: %v", err)
-			}
-		})
+		// register the job in the scheduler that'll trigger it at job start time
+		err := ru.sc.Add(j, time.Now().Add(timeUntilStart))
+		if err != nil {
+			return 0, fmt.Errorf("could not schedule job: %w", err)
+		}
 	}
 	return jobID, nil
 }
@@ -166,149 +319,235 @@ func (ru *RegisterUsecase) GetJobs() ([]models.Job, error) {
 		return nil, err
 	}
 
+	for i := range jobs {
+		ru.setNextRun(&jobs[i])
+	}
+
 	return jobs, err
 }
 
+// setNextRun adds the next fire time of a scheduled job
+func (ru *RegisterUsecase) setNextRun(j *models.Job) {
+	next, exists := ru.sc.Next(j.ID)
+	if exists {
+		j.NextRun = &next
+	}
+}
+
+// DefaultRunsLimit is the number of runs of a job history when none is asked
+const DefaultRunsLimit = 20
+
+// MaxRunsLimit is the highest number of runs of a job history
+const MaxRunsLimit = 100
+
+// GetJobRuns returns the execution history of a job, its limit most recent runs
+func (ru *RegisterUsecase) GetJobRuns(id, limit int) ([]models.JobRun, error) {
+	runs, err := ru.rr.RetrieveRuns(id, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
+
 func (ru *RegisterUsecase) ReloadJobs() error {
 	jobs, err := ru.GetJobs()
 	if err != nil {
 		return fmt.Errorf("could not retrieve jobs: %w", err)
 	}
 
-	now := time.Now()
-
 	for _, job := range jobs {
-		if job.Schedule.Before(now) {
-			nextSchedule := calculateNextValidSchedule(&job)
-			timeUntilStart := time.Until(nextSchedule)
-			job.Schedule = nextSchedule.Local()
+		// the job stays scheduled, its tasks can be fixed by updating it
+		err := validateTasks(job)
+		if err != nil {
+			slog.Warn("job has invalid tasks, its runs will fail, see 'scheduler check'", "job", job.ID, "error", err)
+		}
 
-			err := ru.SetCronFrequency(&job)
-			if err != nil {
-				return fmt.Errorf("could not set cron time on reload jobs: %w", err)
-			}
+		if job.Paused {
+			slog.Info("job is paused, not scheduled", "job", job.ID)
+			continue
+		}
 
-			log.Printf("\n job '%v' - %d rescheduled to run at %v (in %v)", job.Label, job.ID, nextSchedule, timeUntilStart)
-			ru.RegisterJob(job, timeUntilStart, true)
-		} else {
-			timeUntilStart := time.Until(job.Schedule)
-			log.Printf("job %d scheduled to run at %v (in %v)", job.ID, job.Schedule.Local(), timeUntilStart)
-			ru.RegisterJob(job, timeUntilStart, true)
+		err = ru.scheduleJob(job)
+		if err != nil {
+			return err
+		}
+
+		err = ru.catchUp(job)
+		if err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
-func calculateNextValidSchedule(j *models.Job) time.Time {
-	currentTime := time.Now()
-	scheduledTime := j.Schedule
-
-	var nextRun time.Time
-
-	hour := scheduledTime.Hour()
-	minute := scheduledTime.Minute()
-	day := scheduledTime.Day()
-	weekday := scheduledTime.Weekday()
-	month := scheduledTime.Month()
-
-	switch j.Frequency {
-	case "m": // Every minute
-		nextRun = currentTime.Truncate(time.Minute).Add(time.Minute)
+// catchUp runs the occurrences of a job missed since its last scheduled run according to its
+// misfire policy, the runs are recorded with the catch_up trigger
+func (ru *RegisterUsecase) catchUp(job models.Job) error {
+	limit := 1
+	switch job.Misfire {
+	case models.MisfireRunOnce:
+	case models.MisfireRunAll:
+		limit = job.MisfireLimit
+		if limit == 0 {
+			limit = models.DefaultMisfireLimit
+		}
+	default:
+		return nil
+	}
 
-	case "H": // Every hour
-		nextRun = currentTime.Truncate(time.Hour).Add(time.Hour).Add(time.Duration(minute) * time.Minute)
+	last, err := ru.rr.RetrieveLastRun(job.ID)
+	if err != nil {
+		return fmt.Errorf("could not retrieve last run of job %d: %w", job.ID, err)
+	}
 
-	case "D": // Daily
-		nextRun = time.Date(currentTime.Year(), currentTime.Month(), currentTime.Day(), hour, minute, 0, 0, scheduledTime.Location())
-		if currentTime.After(nextRun) {
-			nextRun = nextRun.Add(24 * time.Hour)
-		}
+	missed, err := helpers.MissedRuns(job, last, time.Now(), limit)
+	if err != nil {
+		return fmt.Errorf("could not compute missed runs of job %d: %w", job.ID, err)
+	}
+	if len(missed) == 0 {
+		return nil
+	}
 
-	case "W": // Weekly
-		nextRun = time.Date(currentTime.Year(), currentTime.Month(), currentTime.Day(), hour, minute, 0, 0, scheduledTime.Location())
-		if currentTime.Weekday() > weekday || (currentTime.Weekday() == weekday && currentTime.After(nextRun)) {
-			nextRun = nextRun.AddDate(0, 0, 7)
-		}
+	slog.Info("catching up missed runs", "job", job.ID, "missed", len(missed), "since", missed[0])
+	return ru.sc.CatchUp(job, missed)
+}
 
-	case "M": // Monthly
-		nextRun = time.Date(currentTime.Year(), currentTime.Month(), day, hour, minute, 0, 0, scheduledTime.Location())
-		if currentTime.After(nextRun) {
-			nextRun = nextRun.AddDate(0, 1, 0)
-		}
+// scheduleJob registers the timers of a job already saved in database,
+// a job whose schedule is in the past is moved to its next valid schedule
+func (ru *RegisterUsecase) scheduleJob(job models.Job) error {
+	// the cron frequency follows the saved schedule, not the rescheduled one which may
+	// have been moved by a DST change
+	err := ru.SetCronFrequency(&job)
+	if err != nil {
+		return fmt.Errorf("could not set cron time on reload jobs: %w", err)
+	}
 
-	case "Y": // Yearly
-		nextRun = time.Date(currentTime.Year(), month, day, hour, minute, 0, 0, scheduledTime.Location())
-		if currentTime.After(nextRun) {
-			nextRun = nextRun.AddDate(1, 0, 0)
+	if job.Schedule.Before(time.Now()) {
+		nextSchedule, err := helpers.NextRun(job, time.Now())
+		if err != nil {
+			return fmt.Errorf("could not reschedule job %d: %w", job.ID, err)
 		}
+		timeUntilStart := time.Until(nextSchedule)
+		job.Schedule = nextSchedule.Local()
 
-	default:
-		log.Printf("unsupported frequency: %s", j.Frequency)
-		return time.Time{}
+		slog.Info("job rescheduled", "job", job.ID, "label", job.Label, "at", nextSchedule, "in", timeUntilStart)
+		_, err = ru.RegisterJob(job, timeUntilStart, true)
+		return err
 	}
 
-	return nextRun.Local()
+	timeUntilStart := time.Until(job.Schedule)
+	slog.Info("job scheduled", "job", job.ID, "at", job.Schedule.Local(), "in", timeUntilStart)
+	_, err = ru.RegisterJob(job, timeUntilStart, true)
+	return err
 }
 
 // JobHandler handles job execution and management
 type JobHandler struct {
-	j  *models.Job
-	cs *cron.Cron
 	rr repositories.RegisterInterface
+	ex *Executor
 }
 
 // NewJobHandler creates a new JobHandler
-func NewJobHandler(j *models.Job, cs *cron.Cron, rr repositories.RegisterInterface) *JobHandler {
+func NewJobHandler(rr repositories.RegisterInterface, ex *Executor) *JobHandler {
 	return &JobHandler{
-		j:  j,
-		cs: cs,
 		rr: rr,
+		ex: ex,
 	}
 }
 
-// handleJob runs the job and manages its scheduling
-func handleJob(j *models.Job, rr repositories.RegisterInterface) error {
-	cronJob := cron.New()
-	job := NewJobHandler(j, cronJob, rr)
-	job.Run()
+// Run executes the job and manages its occurrences, it returns the outcome
+// of the run and false once the job has no occurrence left
+func (jh *JobHandler) Run(ctx context.Context, j *models.Job, opts models.RunOptions) (models.JobRun, bool) {
+	slog.Info("run job", "job", j.ID, "trigger", opts.Trigger)
+
+	run := models.JobRun{
+		JobID:       j.ID,
+		Trigger:     opts.Trigger,
+		Status:      models.RunStatusRunning,
+		ScheduledAt: opts.ScheduledAt,
+		StartedAt:   time.Now(),
+	}
 
-	if !j.IsOneTime {
-		err := job.cs.AddJob(j.CronTime, job)
+	saved, err := jh.rr.CreateRun(run)
+	if err != nil {
+		slog.Error("could not save run", "job", j.ID, "error", err)
+	} else {
+		run = saved
+	}
+
+	if opts.OnStart != nil {
+		opts.OnStart(run)
+	}
+
+	jh.ex.ExecuteWorkflow(ctx, j, &run)
+
+	// a run without id could not be saved at start, there is nothing to complete
+	if run.ID != 0 {
+		err = jh.rr.CompleteRun(run)
 		if err != nil {
-			return err
+			slog.Error("could not save outcome of run", "run", run.ID, "error", err)
 		}
-		log.Printf("scheduled job --  %v every %v", j.ID, j.Frequency)
-		job.cs.Start()
 	}
 
-	return nil
-}
-
-// Run executes the job and manages its occurrences
-func (j *JobHandler) Run() {
-	log.Printf("run job -- %v", j.j.ID)
+	for _, t := range run.Tasks {
+		slog.Debug("run task", "job", j.ID, "action", t.Action, "status", t.Status)
+	}
 
-	for _, v := range j.j.Workflow {
-		log.Printf("run task -- %v on job %v", v.Action, v.JobID)
+	if run.Status != models.RunStatusSuccess {
+		slog.Warn("job run did not succeed", "job", j.ID, "status", run.Status, "error", run.Error)
 	}
 
-	if j.j.Occurrences != -1 {
-		occurrencesLeft, err := j.rr.DecrementJobOccurrences(j.j.ID)
+	if opts.ConsumeOccurrence && j.Occurrences != -1 {
+		occurrencesLeft, err := jh.rr.DecrementJobOccurrences(j.ID)
 		if err != nil {
-			log.Printf("could not decrement Occurrences: %v with error: %v", j.j.ID, err)
-			return
+			slog.Error("could not decrement occurrences", "job", j.ID, "error", err)
+			return run, true
 		}
 
-		log.Printf("occurrences decrement -- job %v occurrences: %d", j.j.ID, occurrencesLeft)
+		slog.Debug("occurrences decremented", "job", j.ID, "occurrences", occurrencesLeft)
 		if occurrencesLeft == 0 {
-			log.Printf("job deletion -- %v", j.j.ID)
-			err := j.rr.DeleteJob(j.j.ID)
+			slog.Info("no occurrence left, deleting job", "job", j.ID)
+			err := jh.rr.DeleteJob(j.ID)
 			if err != nil {
-				log.Printf("could not delete job: %v with error: %v", j.j.ID, err)
-				return
+				slog.Error("could not delete job", "job", j.ID, "error", err)
 			}
-			j.cs.Stop()
+			return run, false
+		}
+	}
+
+	return run, true
+}
+
+// Skip records an execution of a job that did not run, it never consumes an occurrence
+func (jh *JobHandler) Skip(j *models.Job, opts models.RunOptions, reason string) models.JobRun {
+	now := time.Now()
+	run := models.JobRun{
+		JobID:       j.ID,
+		Trigger:     opts.Trigger,
+		Status:      models.RunStatusSkipped,
+		ScheduledAt: opts.ScheduledAt,
+		StartedAt:   now,
+	}
+
+	saved, err := jh.rr.CreateRun(run)
+	if err != nil {
+		slog.Error("could not save run", "job", j.ID, "error", err)
+	} else {
+		run = saved
+	}
+
+	run.Status = models.RunStatusSkipped
+	run.Error = reason
+	run.EndedAt = now
+
+	if run.ID != 0 {
+		err = jh.rr.CompleteRun(run)
+		if err != nil {
+			slog.Error("could not save outcome of run", "run", run.ID, "error", err)
 		}
 	}
+
+	return run
 }
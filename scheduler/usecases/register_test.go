@@ -0,0 +1,230 @@
+package usecases
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tobg/scheduler/models"
+	"github.com/tobg/scheduler/repositories"
+)
+
+func TestJobHandlerRun(t *testing.T) {
+	tasks := map[string]models.TaskHandler{
+		"ok": {
+			Execute: func(ctx context.Context, params models.Params) (models.TaskOutputs, error) { return nil, nil },
+		},
+		"ko": {
+			Execute: func(ctx context.Context, params models.Params) (models.TaskOutputs, error) {
+				return nil, errors.New("boom")
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		occurrences     int
+		action          string
+		opts            models.RunOptions
+		wantStatus      models.RunStatus
+		wantAgain       bool
+		wantOccurrences int
+		wantDeleted     bool
+	}{
+		"nominal, consume an occurrence": {
+			occurrences:     3,
+			action:          "ok",
+			opts:            models.RunOptions{Trigger: models.RunTriggerScheduled, ConsumeOccurrence: true},
+			wantStatus:      models.RunStatusSuccess,
+			wantAgain:       true,
+			wantOccurrences: 2,
+		},
+		"nominal, failed run consumes an occurrence": {
+			occurrences:     3,
+			action:          "ko",
+			opts:            models.RunOptions{Trigger: models.RunTriggerScheduled, ConsumeOccurrence: true},
+			wantStatus:      models.RunStatusFailed,
+			wantAgain:       true,
+			wantOccurrences: 2,
+		},
+		"nominal, infinite occurrences": {
+			occurrences:     -1,
+			action:          "ok",
+			opts:            models.RunOptions{Trigger: models.RunTriggerScheduled, ConsumeOccurrence: true},
+			wantStatus:      models.RunStatusSuccess,
+			wantAgain:       true,
+			wantOccurrences: -1,
+		},
+		"nominal, manual run keeps occurrences": {
+			occurrences:     1,
+			action:          "ok",
+			opts:            models.RunOptions{Trigger: models.RunTriggerManual},
+			wantStatus:      models.RunStatusSuccess,
+			wantAgain:       true,
+			wantOccurrences: 1,
+		},
+		"last occurrence, delete job": {
+			occurrences: 1,
+			action:      "ok",
+			opts:        models.RunOptions{Trigger: models.RunTriggerScheduled, ConsumeOccurrence: true},
+			wantStatus:  models.RunStatusSuccess,
+			wantAgain:   false,
+			wantDeleted: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			rr := repositories.NewMemoryRepository()
+			jh := NewJobHandler(rr, NewExecutor(tasks, DefaultMaxParallelTasks))
+
+			id, err := rr.RegisterJob(models.Job{Label: "backup", Frequency: "D", Occurrences: tt.occurrences, Workflow: []models.Task{{Action: tt.action}}})
+			require.NoError(t, err)
+			j, err := rr.RetrieveJob(id)
+			require.NoError(t, err)
+
+			run, again := jh.Run(context.Background(), &j, tt.opts)
+			assert.Equal(t, tt.wantStatus, run.Status)
+			assert.Equal(t, tt.wantAgain, again)
+
+			runs, err := rr.RetrieveRuns(id, 10)
+			require.NoError(t, err)
+			require.Len(t, runs, 1, "the run is saved")
+			assert.Equal(t, tt.wantStatus, runs[0].Status)
+			assert.Equal(t, tt.opts.Trigger, runs[0].Trigger)
+			assert.Len(t, runs[0].Tasks, 1)
+
+			got, err := rr.RetrieveJob(id)
+			if tt.wantDeleted {
+				assert.ErrorIs(t, err, repositories.ErrJobNotFound)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantOccurrences, got.Occurrences)
+		})
+	}
+}
+
+// failingRunsRepository is a memory repository which could not save the runs
+type failingRunsRepository struct {
+	repositories.RegisterInterface
+}
+
+func (f failingRunsRepository) CreateRun(run models.JobRun) (models.JobRun, error) {
+	return models.JobRun{}, errors.New("disk is full")
+}
+
+func TestJobHandlerRunLogsErrors(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn})))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	tasks := map[string]models.TaskHandler{
+		"ok": {
+			Execute: func(ctx context.Context, params models.Params) (models.TaskOutputs, error) { return nil, nil },
+		},
+	}
+	rr := failingRunsRepository{repositories.NewMemoryRepository()}
+	jh := NewJobHandler(rr, NewExecutor(tasks, DefaultMaxParallelTasks))
+
+	id, err := rr.RegisterJob(models.Job{Label: "backup", Frequency: "D", Occurrences: -1, Workflow: []models.Task{{Action: "ok"}}})
+	require.NoError(t, err)
+	j, err := rr.RetrieveJob(id)
+	require.NoError(t, err)
+
+	jh.Run(context.Background(), &j, models.RunOptions{Trigger: models.RunTriggerManual})
+
+	assert.Contains(t, buf.String(), "level=ERROR")
+	assert.Contains(t, buf.String(), "could not save run")
+	assert.Contains(t, buf.String(), "disk is full")
+	assert.NotContains(t, buf.String(), "run job", "info events are below the warn level")
+}
+
+func TestTriggerPausedJob(t *testing.T) {
+	rr := repositories.NewMemoryRepository()
+	jh := NewJobHandler(rr, NewExecutor(map[string]models.TaskHandler{
+		"ok": {
+			Execute: func(ctx context.Context, params models.Params) (models.TaskOutputs, error) { return nil, nil },
+		},
+	}, DefaultMaxParallelTasks))
+	sc := NewScheduler(jh)
+	ru := NewRegisterUsecase(rr, sc)
+
+	id, err := rr.RegisterJob(models.Job{Label: "backup", Frequency: "D", Occurrences: -1, Workflow: []models.Task{{Action: "ok"}}})
+	require.NoError(t, err)
+	require.NoError(t, ru.PauseJob(id))
+
+	j, err := ru.GetJob(id)
+	require.NoError(t, err)
+	require.True(t, j.Paused)
+
+	// pausing only stops the scheduled runs, a manual run goes on
+	run, err := ru.TriggerJob(j, models.TriggerRequest{})
+	require.NoError(t, err)
+	assert.NotZero(t, run.ID)
+	assert.Equal(t, models.RunStatusRunning, run.Status)
+
+	require.NoError(t, sc.Stop(context.Background()))
+
+	runs, err := rr.RetrieveRuns(id, 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, run.ID, runs[0].ID)
+	assert.Equal(t, models.RunStatusSuccess, runs[0].Status)
+
+	_, scheduled := sc.Next(id)
+	assert.False(t, scheduled, "the job stays paused")
+}
+
+func TestDeleteJob(t *testing.T) {
+	rr := repositories.NewMemoryRepository()
+	sc := NewScheduler(&fakeRunner{})
+	ru := NewRegisterUsecase(rr, sc)
+
+	id, err := rr.RegisterJob(models.Job{Label: "backup", Frequency: "D", Occurrences: -1, Workflow: []models.Task{{Action: "ok"}}})
+	require.NoError(t, err)
+
+	require.NoError(t, ru.DeleteJob(id))
+
+	_, err = ru.GetJob(id)
+	assert.ErrorIs(t, err, repositories.ErrJobNotFound)
+
+	err = ru.DeleteJob(id)
+	assert.ErrorIs(t, err, repositories.ErrJobNotFound)
+}
+
+// storedJobsRepository returns jobs as they were stored by older versions
+type storedJobsRepository struct {
+	repositories.RegisterInterface
+	jobs []models.Job
+}
+
+func (s storedJobsRepository) RetrieveJobs() ([]models.Job, error) {
+	return s.jobs, nil
+}
+
+func TestCheckJobs(t *testing.T) {
+	rr := storedJobsRepository{jobs: []models.Job{
+		{ID: 1, Label: "release", Workflow: []models.Task{
+			{Action: "deploy", Args: []string{"web", "/home/apps/web", "/srv/artifacts/web.tar.gz"}},
+		}},
+		// the deploy task took the service and the deployment path before it installed artifacts
+		{ID: 2, Label: "legacy", Workflow: []models.Task{
+			{Action: "deploy", Args: []string{"web", "/home/apps/web"}},
+		}},
+		{ID: 3, Label: "notify", Workflow: []models.Task{
+			{Action: "http", Params: models.Params{"method": "POST", "url": "https://hooks.example.com/deploy"}},
+		}},
+	}}
+
+	invalid, err := CheckJobs(rr)
+	require.NoError(t, err)
+	require.Len(t, invalid, 1)
+	assert.Equal(t, 2, invalid[0].ID)
+	assert.Equal(t, "legacy", invalid[0].Label)
+	assert.ErrorContains(t, invalid[0].Err, "invalid number of arguments")
+}
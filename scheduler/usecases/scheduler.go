@@ -0,0 +1,348 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron"
+	"github.com/tobg/scheduler/helpers"
+	"github.com/tobg/scheduler/models"
+)
+
+// JobRunner executes a job, it returns false once the job must not fire anymore.
+// Skip records an execution prevented by the concurrency policy of the job.
+type JobRunner interface {
+	Run(ctx context.Context, j *models.Job, opts models.RunOptions) (models.JobRun, bool)
+	Skip(j *models.Job, opts models.RunOptions, reason string) models.JobRun
+}
+
+// Scheduler owns the timers of every scheduled job
+type Scheduler struct {
+	runner JobRunner
+
+	// ctx is the parent of every execution, it is cancelled when a stop times out
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	entries    map[int]*entry
+	executions map[int][]*execution
+	stopped    bool
+	running    sync.WaitGroup
+}
+
+// execution is a running execution of a job
+type execution struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// entry is a scheduled job, recurring jobs have a cron schedule
+type entry struct {
+	job      models.Job
+	schedule cron.Schedule
+	next     time.Time
+	timer    *time.Timer
+}
+
+// NewScheduler returns a scheduler executing fired jobs with the runner
+func NewScheduler(runner JobRunner) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Scheduler{
+		runner:     runner,
+		ctx:        ctx,
+		cancel:     cancel,
+		entries:    make(map[int]*entry),
+		executions: make(map[int][]*execution),
+	}
+}
+
+// Add schedules a job to fire at start then, unless it is a one time job,
+// at each time of its cron frequency. A job already scheduled is replaced.
+func (s *Scheduler) Add(j models.Job, start time.Time) error {
+	e := &entry{
+		job:  j,
+		next: start,
+	}
+
+	if !j.IsOneTime {
+		schedule, err := helpers.JobSchedule(j.CronTime, j.Timezone)
+		if err != nil {
+			return fmt.Errorf("invalid cron time %v: %w", j.CronTime, err)
+		}
+		e.schedule = schedule
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return fmt.Errorf("scheduler is stopped")
+	}
+
+	s.remove(j.ID)
+	s.entries[j.ID] = e
+	e.timer = time.AfterFunc(time.Until(start), func() { s.fire(e) })
+
+	return nil
+}
+
+// Remove cancels the pending executions of a job
+func (s *Scheduler) Remove(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(id)
+}
+
+// Reschedule replaces the schedule of a job
+func (s *Scheduler) Reschedule(j models.Job, start time.Time) error {
+	return s.Add(j, start)
+}
+
+// List returns the scheduled jobs and their next fire time
+func (s *Scheduler) List() []models.ScheduleEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]models.ScheduleEntry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, models.ScheduleEntry{
+			JobID: e.job.ID,
+			Label: e.job.Label,
+			Next:  e.next,
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].JobID < entries[j].JobID
+	})
+
+	return entries
+}
+
+// Next returns the next fire time of a job
+func (s *Scheduler) Next(id int) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, exists := s.entries[id]
+	if !exists {
+		return time.Time{}, false
+	}
+	return e.next, true
+}
+
+// Stop cancels every timer and waits for the running executions to end.
+// Once the context is done the running executions are cancelled.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopped = true
+	for id := range s.entries {
+		s.remove(id)
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		slog.Info("scheduler stopped")
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		return fmt.Errorf("could not wait for running jobs: %w", ctx.Err())
+	}
+}
+
+// remove must be called with the lock held
+func (s *Scheduler) remove(id int) {
+	e, exists := s.entries[id]
+	if !exists {
+		return
+	}
+
+	e.timer.Stop()
+	delete(s.entries, id)
+}
+
+// fire arms the next execution of a recurring job before running it,
+// so a slow execution never delays the following ones
+func (s *Scheduler) fire(e *entry) {
+	s.mu.Lock()
+	if s.entries[e.job.ID] != e {
+		// removed or replaced meanwhile
+		s.mu.Unlock()
+		return
+	}
+
+	scheduledAt := e.next
+	if e.schedule != nil {
+		e.next = e.schedule.Next(time.Now())
+		e.timer = time.AfterFunc(time.Until(e.next), func() { s.fire(e) })
+	} else {
+		delete(s.entries, e.job.ID)
+	}
+
+	s.running.Add(1)
+	s.mu.Unlock()
+	defer s.running.Done()
+
+	job := e.job
+	_, again := s.execute(&job, models.RunOptions{
+		Trigger:           models.RunTriggerScheduled,
+		ConsumeOccurrence: true,
+		ScheduledAt:       scheduledAt,
+	})
+	if !again {
+		s.mu.Lock()
+		if s.entries[e.job.ID] == e {
+			s.remove(e.job.ID)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Trigger runs a job immediately, outside of its schedule, in the background. It returns the
+// run once started, or its outcome when the runner reports no start (a skipped run).
+// A job left without occurrences is removed from the scheduler.
+func (s *Scheduler) Trigger(j models.Job, opts models.RunOptions) (models.JobRun, error) {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return models.JobRun{}, fmt.Errorf("scheduler is stopped")
+	}
+	s.running.Add(1)
+	s.mu.Unlock()
+
+	if opts.ScheduledAt.IsZero() {
+		opts.ScheduledAt = time.Now()
+	}
+
+	started := make(chan models.JobRun, 1)
+	opts.OnStart = func(run models.JobRun) {
+		started <- run
+	}
+
+	go func() {
+		defer s.running.Done()
+
+		run, again := s.execute(&j, opts)
+		if !again {
+			s.Remove(j.ID)
+		}
+
+		// the run did not start, its outcome is returned instead
+		select {
+		case started <- run:
+		default:
+		}
+	}()
+
+	return <-started, nil
+}
+
+// CatchUp runs the missed occurrences of a job one after the other, in the background.
+// The catch up stops once the job is left without occurrences or the scheduler stops.
+func (s *Scheduler) CatchUp(j models.Job, missed []time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return fmt.Errorf("scheduler is stopped")
+	}
+	s.running.Add(1)
+
+	go func() {
+		defer s.running.Done()
+
+		for _, scheduledAt := range missed {
+			if s.ctx.Err() != nil || s.isStopped() {
+				return
+			}
+
+			job := j
+			_, again := s.execute(&job, models.RunOptions{
+				Trigger:           models.RunTriggerCatchUp,
+				ConsumeOccurrence: true,
+				ScheduledAt:       scheduledAt,
+			})
+			if !again {
+				s.Remove(j.ID)
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// isStopped returns wether or not the scheduler is stopped
+func (s *Scheduler) isStopped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stopped
+}
+
+// execute runs a job according to its concurrency policy: allow starts it right away,
+// forbid skips it while another execution is running and replace cancels the running
+// executions and waits for them to end before starting it
+func (s *Scheduler) execute(j *models.Job, opts models.RunOptions) (models.JobRun, bool) {
+	s.mu.Lock()
+	for len(s.executions[j.ID]) > 0 {
+		running := s.executions[j.ID]
+
+		if j.Concurrency == models.ConcurrencyForbid {
+			s.mu.Unlock()
+			slog.Warn("job skipped, previous execution still running", "job", j.ID)
+			return s.runner.Skip(j, opts, "previous execution still running"), true
+		}
+		if j.Concurrency != models.ConcurrencyReplace {
+			break
+		}
+
+		s.mu.Unlock()
+		slog.Info("job replaces running executions", "job", j.ID, "running", len(running))
+		for _, ex := range running {
+			ex.cancel()
+			<-ex.done
+		}
+		s.mu.Lock()
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	ex := &execution{cancel: cancel, done: make(chan struct{})}
+	s.executions[j.ID] = append(s.executions[j.ID], ex)
+	s.mu.Unlock()
+
+	defer func() {
+		cancel()
+
+		s.mu.Lock()
+		running := s.executions[j.ID]
+		for i := range running {
+			if running[i] == ex {
+				s.executions[j.ID] = append(running[:i:i], running[i+1:]...)
+				break
+			}
+		}
+		if len(s.executions[j.ID]) == 0 {
+			delete(s.executions, j.ID)
+		}
+		s.mu.Unlock()
+
+		close(ex.done)
+	}()
+
+	return s.runner.Run(ctx, j, opts)
+}
@@ -0,0 +1,313 @@
+package usecases
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tobg/scheduler/models"
+)
+
+// fakeRunner reports each fired job on a channel
+type fakeRunner struct {
+	fired chan int
+	again bool
+}
+
+func (f *fakeRunner) Run(ctx context.Context, j *models.Job, opts models.RunOptions) (models.JobRun, bool) {
+	f.fired <- j.ID
+	return models.JobRun{JobID: j.ID, Trigger: opts.Trigger}, f.again
+}
+
+func (f *fakeRunner) Skip(j *models.Job, opts models.RunOptions, reason string) models.JobRun {
+	return models.JobRun{JobID: j.ID, Trigger: opts.Trigger, Status: models.RunStatusSkipped, Error: reason}
+}
+
+// blockingRunner runs jobs until they are released or cancelled
+type blockingRunner struct {
+	fakeRunner
+	started chan int
+	release chan struct{}
+}
+
+func (b *blockingRunner) Run(ctx context.Context, j *models.Job, opts models.RunOptions) (models.JobRun, bool) {
+	b.started <- j.ID
+
+	select {
+	case <-b.release:
+		return models.JobRun{JobID: j.ID, Status: models.RunStatusSuccess}, true
+	case <-ctx.Done():
+		return models.JobRun{JobID: j.ID, Status: models.RunStatusCancelled}, true
+	}
+}
+
+func TestSchedulerFire(t *testing.T) {
+	tests := map[string]struct {
+		job       models.Job
+		again     bool
+		wantEntry bool
+	}{
+		"one time job, removed after firing": {
+			job:       models.Job{ID: 1, IsOneTime: true},
+			again:     true,
+			wantEntry: false,
+		},
+		"recurring job, scheduled again": {
+			job:       models.Job{ID: 2, CronTime: "@every 1h"},
+			again:     true,
+			wantEntry: true,
+		},
+		"recurring job without occurrences left, removed": {
+			job:       models.Job{ID: 3, CronTime: "@every 1h"},
+			again:     false,
+			wantEntry: false,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			runner := &fakeRunner{fired: make(chan int, 1), again: tt.again}
+			sc := NewScheduler(runner)
+
+			require.NoError(t, sc.Add(tt.job, time.Now().Add(10*time.Millisecond)))
+
+			select {
+			case id := <-runner.fired:
+				assert.Equal(t, tt.job.ID, id)
+			case <-time.After(time.Second):
+				t.Fatal("job did not fire")
+			}
+
+			assert.Eventually(t, func() bool {
+				_, exists := sc.Next(tt.job.ID)
+				return exists == tt.wantEntry
+			}, time.Second, 5*time.Millisecond)
+
+			require.NoError(t, sc.Stop(context.Background()))
+		})
+	}
+}
+
+func TestSchedulerRemove(t *testing.T) {
+	runner := &fakeRunner{fired: make(chan int, 1), again: true}
+	sc := NewScheduler(runner)
+
+	require.NoError(t, sc.Add(models.Job{ID: 1, CronTime: "@every 1h"}, time.Now().Add(20*time.Millisecond)))
+	require.NoError(t, sc.Add(models.Job{ID: 2, CronTime: "@every 1h"}, time.Now().Add(time.Hour)))
+
+	sc.Remove(1)
+
+	select {
+	case <-runner.fired:
+		t.Fatal("removed job fired")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	entries := sc.List()
+	require.Len(t, entries, 1)
+	assert.Equal(t, 2, entries[0].JobID)
+
+	_, exists := sc.Next(1)
+	assert.False(t, exists)
+}
+
+func TestSchedulerAddInvalidCron(t *testing.T) {
+	sc := NewScheduler(&fakeRunner{})
+
+	err := sc.Add(models.Job{ID: 1, CronTime: "invalid"}, time.Now())
+	assert.Error(t, err)
+}
+
+func TestSchedulerAddInvalidTimezone(t *testing.T) {
+	sc := NewScheduler(&fakeRunner{})
+
+	err := sc.Add(models.Job{ID: 1, CronTime: "@daily", Timezone: "Mars/Olympus_Mons"}, time.Now())
+	assert.Error(t, err)
+}
+
+func TestSchedulerStopped(t *testing.T) {
+	sc := NewScheduler(&fakeRunner{})
+	require.NoError(t, sc.Stop(context.Background()))
+
+	err := sc.Add(models.Job{ID: 1, IsOneTime: true}, time.Now())
+	assert.Error(t, err)
+}
+
+func TestSchedulerTrigger(t *testing.T) {
+	runner := &fakeRunner{fired: make(chan int, 1), again: false}
+	sc := NewScheduler(runner)
+
+	require.NoError(t, sc.Add(models.Job{ID: 1, CronTime: "@every 1h"}, time.Now().Add(time.Hour)))
+
+	run, err := sc.Trigger(models.Job{ID: 1}, models.RunOptions{Trigger: models.RunTriggerManual})
+	require.NoError(t, err)
+	assert.Equal(t, models.RunTriggerManual, run.Trigger)
+	assert.Equal(t, 1, <-runner.fired)
+
+	// the runner reported no occurrence left
+	_, exists := sc.Next(1)
+	assert.False(t, exists)
+}
+
+// startingRunner reports the start of its runs then runs them until they are released
+type startingRunner struct {
+	blockingRunner
+}
+
+func (s *startingRunner) Run(ctx context.Context, j *models.Job, opts models.RunOptions) (models.JobRun, bool) {
+	opts.OnStart(models.JobRun{ID: 7, JobID: j.ID, Status: models.RunStatusRunning})
+	return s.blockingRunner.Run(ctx, j, opts)
+}
+
+func TestSchedulerTriggerInBackground(t *testing.T) {
+	runner := &startingRunner{blockingRunner{started: make(chan int, 1), release: make(chan struct{})}}
+	sc := NewScheduler(runner)
+
+	run, err := sc.Trigger(models.Job{ID: 1}, models.RunOptions{Trigger: models.RunTriggerManual})
+	require.NoError(t, err)
+	assert.Equal(t, models.JobRun{ID: 7, JobID: 1, Status: models.RunStatusRunning}, run, "the run is returned once started")
+	assert.Equal(t, 1, <-runner.started)
+
+	close(runner.release)
+	require.NoError(t, sc.Stop(context.Background()))
+}
+
+// recordingRunner records the options of each run, the job runs out of occurrences after left runs
+type recordingRunner struct {
+	fakeRunner
+	mu   sync.Mutex
+	runs []models.RunOptions
+	left int
+}
+
+func (r *recordingRunner) Run(ctx context.Context, j *models.Job, opts models.RunOptions) (models.JobRun, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.runs = append(r.runs, opts)
+	r.left--
+	return models.JobRun{JobID: j.ID, Trigger: opts.Trigger, ScheduledAt: opts.ScheduledAt}, r.left > 0
+}
+
+func TestSchedulerCatchUp(t *testing.T) {
+	missed := []time.Time{
+		time.Date(2025, time.March, 1, 2, 0, 0, 0, time.UTC),
+		time.Date(2025, time.March, 2, 2, 0, 0, 0, time.UTC),
+		time.Date(2025, time.March, 3, 2, 0, 0, 0, time.UTC),
+	}
+
+	tests := map[string]struct {
+		left      int
+		wantRuns  int
+		wantEntry bool
+	}{
+		"nominal, every missed occurrence runs in order": {
+			left:      10,
+			wantRuns:  3,
+			wantEntry: true,
+		},
+		"occurrences exhausted, catch up stops and job removed": {
+			left:      2,
+			wantRuns:  2,
+			wantEntry: false,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			runner := &recordingRunner{left: tt.left}
+			sc := NewScheduler(runner)
+
+			job := models.Job{ID: 1, CronTime: "@daily"}
+			require.NoError(t, sc.Add(job, time.Now().Add(time.Hour)))
+			require.NoError(t, sc.CatchUp(job, missed))
+
+			assert.Eventually(t, func() bool {
+				runner.mu.Lock()
+				defer runner.mu.Unlock()
+				return len(runner.runs) == tt.wantRuns
+			}, time.Second, 5*time.Millisecond)
+
+			assert.Eventually(t, func() bool {
+				_, exists := sc.Next(job.ID)
+				return exists == tt.wantEntry
+			}, time.Second, 5*time.Millisecond)
+			require.NoError(t, sc.Stop(context.Background()))
+
+			assert.Len(t, runner.runs, tt.wantRuns)
+			for i, opts := range runner.runs {
+				assert.Equal(t, models.RunTriggerCatchUp, opts.Trigger)
+				assert.True(t, opts.ConsumeOccurrence)
+				assert.Equal(t, missed[i], opts.ScheduledAt)
+			}
+		})
+	}
+}
+
+func TestSchedulerConcurrencyPolicy(t *testing.T) {
+	tests := map[string]struct {
+		policy      models.ConcurrencyPolicy
+		wantFirst   models.RunStatus
+		wantSecond  models.RunStatus
+		wantStarted int
+	}{
+		"allow, both executions run": {
+			policy:      models.ConcurrencyAllow,
+			wantFirst:   models.RunStatusSuccess,
+			wantSecond:  models.RunStatusSuccess,
+			wantStarted: 2,
+		},
+		"forbid, second execution skipped": {
+			policy:      models.ConcurrencyForbid,
+			wantFirst:   models.RunStatusSuccess,
+			wantSecond:  models.RunStatusSkipped,
+			wantStarted: 1,
+		},
+		"replace, first execution cancelled": {
+			policy:      models.ConcurrencyReplace,
+			wantFirst:   models.RunStatusCancelled,
+			wantSecond:  models.RunStatusSuccess,
+			wantStarted: 2,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			runner := &blockingRunner{started: make(chan int, 2), release: make(chan struct{})}
+			sc := NewScheduler(runner)
+			job := models.Job{ID: 1, Concurrency: tt.policy}
+
+			first := make(chan models.JobRun, 1)
+			go func() {
+				run, _ := sc.Trigger(job, models.RunOptions{Trigger: models.RunTriggerManual})
+				first <- run
+			}()
+			<-runner.started
+
+			second := make(chan models.JobRun, 1)
+			go func() {
+				run, _ := sc.Trigger(job, models.RunOptions{Trigger: models.RunTriggerManual})
+				second <- run
+			}()
+
+			// under replace the second execution only starts once the first one is cancelled
+			for i := 1; i < tt.wantStarted; i++ {
+				<-runner.started
+			}
+			if tt.policy == models.ConcurrencyForbid {
+				assert.Equal(t, tt.wantSecond, (<-second).Status)
+			}
+			close(runner.release)
+
+			assert.Equal(t, tt.wantFirst, (<-first).Status)
+			if tt.policy != models.ConcurrencyForbid {
+				assert.Equal(t, tt.wantSecond, (<-second).Status)
+			}
+
+			require.NoError(t, sc.Stop(context.Background()))
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package usecases
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/tobg/scheduler/helpers/validations"
+	"github.com/tobg/scheduler/models"
+)
+
+// ParseTrigger reads the optional body of a trigger request
+func (ru *RegisterUsecase) ParseTrigger(r *http.Request) (models.TriggerRequest, error) {
+	var tr models.TriggerRequest
+
+	if r.Body == nil {
+		return tr, nil
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&tr)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return models.TriggerRequest{}, err
+	}
+
+	return tr, nil
+}
+
+// ApplyTrigger overrides the params of the job tasks with the ones of the trigger request,
+// the params of the request are merged into the ones of the task
+func (ru *RegisterUsecase) ApplyTrigger(j models.Job, tr models.TriggerRequest) (models.Job, error) {
+	if len(tr.Params) == 0 {
+		return j, nil
+	}
+
+	// copy the workflow, the job tasks must stay untouched
+	workflow := make([]models.Task, len(j.Workflow))
+	copy(workflow, j.Workflow)
+
+	for i, override := range tr.Params {
+		if i < 0 || i >= len(workflow) {
+			return models.Job{}, fmt.Errorf("no task at position %d in workflow", i)
+		}
+
+		params, err := validations.TaskParams(workflow[i])
+		if err != nil {
+			return models.Job{}, err
+		}
+
+		merged := make(models.Params, len(params)+len(override))
+		for k, v := range params {
+			merged[k] = v
+		}
+		for k, v := range override {
+			merged[k] = v
+		}
+
+		workflow[i].Params = merged
+		workflow[i].Args = nil
+		err = validations.IsValidAction(workflow[i])
+		if err != nil {
+			return models.Job{}, err
+		}
+	}
+
+	j.Workflow = workflow
+	return j, nil
+}
+
+// TriggerJob starts a job immediately and returns its run, an occurrence is consumed only
+// when requested. A paused job can be triggered, pausing only stops its scheduled runs.
+func (ru *RegisterUsecase) TriggerJob(j models.Job, tr models.TriggerRequest) (models.JobRun, error) {
+	slog.Info("job triggered manually", "job", j.ID)
+
+	run, err := ru.sc.Trigger(j, models.RunOptions{
+		Trigger:           models.RunTriggerManual,
+		ConsumeOccurrence: tr.ConsumeOccurrence,
+	})
+	if err != nil {
+		return models.JobRun{}, err
+	}
+
+	return run, nil
+}
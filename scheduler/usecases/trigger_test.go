@@ -0,0 +1,90 @@
+package usecases
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tobg/scheduler/models"
+)
+
+func TestApplyTrigger(t *testing.T) {
+	deploy := models.Task{
+		Action: "deploy",
+		Params: models.Params{
+			"service":         "civic-assistant",
+			"deployment_path": "/home/apps/civic-assistant",
+			"artifact":        "/tmp/civic-assistant.tar.gz",
+		},
+	}
+	legacy := models.Task{
+		Action: "deploy",
+		Args:   []string{"civic-assistant", "/home/apps/civic-assistant", "/tmp/civic-assistant.tar.gz"},
+	}
+
+	tests := map[string]struct {
+		task       models.Task
+		tr         models.TriggerRequest
+		wantParams models.Params
+		wantErr    assert.ErrorAssertionFunc
+	}{
+		"nominal, no override": {
+			task:       deploy,
+			tr:         models.TriggerRequest{},
+			wantParams: deploy.Params,
+			wantErr:    assert.NoError,
+		},
+		"nominal, override params": {
+			task: deploy,
+			tr: models.TriggerRequest{
+				Params: map[int]models.Params{0: {"artifact": "/tmp/hotfix.tar.gz"}},
+			},
+			wantParams: models.Params{
+				"service":         "civic-assistant",
+				"deployment_path": "/home/apps/civic-assistant",
+				"artifact":        "/tmp/hotfix.tar.gz",
+			},
+			wantErr: assert.NoError,
+		},
+		"nominal, override params of legacy args": {
+			task: legacy,
+			tr: models.TriggerRequest{
+				Params: map[int]models.Params{0: {"artifact": "/tmp/hotfix.tar.gz"}},
+			},
+			wantParams: models.Params{
+				"service":         "civic-assistant",
+				"deployment_path": "/home/apps/civic-assistant",
+				"artifact":        "/tmp/hotfix.tar.gz",
+			},
+			wantErr: assert.NoError,
+		},
+		"unknown position, return error": {
+			task: deploy,
+			tr: models.TriggerRequest{
+				Params: map[int]models.Params{1: {"artifact": "/tmp/hotfix.tar.gz"}},
+			},
+			wantErr: assert.Error,
+		},
+		"invalid params, return error": {
+			task: deploy,
+			tr: models.TriggerRequest{
+				Params: map[int]models.Params{0: {"artifact": "hotfix.tar.gz"}},
+			},
+			wantErr: assert.Error,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ru := NewRegisterUsecase(nil, nil)
+			job := models.Job{ID: 1, Workflow: []models.Task{tt.task}}
+
+			got, err := ru.ApplyTrigger(job, tt.tr)
+			tt.wantErr(t, err)
+			if err == nil {
+				assert.Equal(t, tt.wantParams, got.Workflow[0].Params)
+			}
+			// the original workflow is never modified
+			assert.Equal(t, tt.task, job.Workflow[0])
+		})
+	}
+}